@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/alex/ralph-tui/src/lib/attach"
+)
+
+// daemonEnv marks the background copy started by --daemon, which serves
+// the loops instead of spawning another daemon.
+const daemonEnv = "RALPH_TUI_DAEMON"
+
+// daemonStartTimeout is how long --daemon waits for the daemon's socket.
+const daemonStartTimeout = 10 * time.Second
+
+// startDaemon re-runs this command with extraArgs in the background,
+// detached from the terminal, and waits until it listens on socket. The
+// daemon's own output goes to daemon.log next to the socket.
+func startDaemon(socket string, extraArgs ...string) error {
+	// Guard: One daemon per socket
+	if attach.Alive(socket) {
+		return fmt.Errorf("a ralph-tui daemon is already running on %s; attach with: ralph-tui attach --socket %s", socket, socket)
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate ralph-tui: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(socket), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(socket), err)
+	}
+	logPath := filepath.Join(filepath.Dir(socket), "daemon.log")
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open daemon log: %w", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(self, append(os.Args[1:], extraArgs...)...)
+	cmd.Env = append(os.Environ(), daemonEnv+"=1")
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	// A new session keeps the daemon alive when the terminal closes
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	deadline := time.After(daemonStartTimeout)
+	for !attach.Alive(socket) {
+		select {
+		case err := <-exited:
+			return fmt.Errorf("daemon exited during startup (%v); see %s", err, logPath)
+		case <-deadline:
+			return fmt.Errorf("daemon did not listen on %s within %s; see %s", socket, daemonStartTimeout, logPath)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	return cmd.Process.Release()
+}
+
+// attachTo connects the terminal to the daemon on socket until the user
+// detaches or the daemon stops.
+func attachTo(socket string) error {
+	conn, err := attach.Dial(socket)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := attach.Run(conn, os.Stdin, os.Stdout); err != nil {
+		return err
+	}
+	if attach.Alive(socket) {
+		fmt.Printf("Detached; the loops keep running. Reattach with: ralph-tui attach --socket %s\n", socket)
+	} else {
+		fmt.Println("ralph-tui daemon stopped")
+	}
+	return nil
+}
+
+// attachMain implements `ralph-tui attach`.
+func attachMain(args []string) {
+	flags := flag.NewFlagSet("attach", flag.ExitOnError)
+	workDir := flags.String("dir", "", "Checkout the daemon was started in")
+	socket := flags.String("socket", "", "Daemon socket (default <dir>/"+attach.DefaultSocket+")")
+	flags.Parse(args)
+
+	path := *socket
+	if path == "" {
+		path = filepath.Join(*workDir, attach.DefaultSocket)
+	}
+	if err := attachTo(path); err != nil {
+		if errors.Is(err, attach.ErrNoDaemon) {
+			fmt.Fprintf(os.Stderr, "Error: %v; start one with: ralph-tui --daemon\n", err)
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/alex/ralph-tui/src/lib/state"
+	"github.com/alex/ralph-tui/src/lib/worktree"
+)
+
+// loopSpec configures one named loop of the TUI.
+type loopSpec struct {
+	name     string
+	mode     state.Mode
+	maxIter  int
+	workDesc string
+	dir      string
+	runner   state.Runner
+	worktree *worktree.Worktree // Set once created with --worktrees
+}
+
+// parseMode converts a --mode value.
+func parseMode(value string) (state.Mode, error) {
+	switch value {
+	case "build":
+		return state.ModeBuild, nil
+	case "plan":
+		return state.ModePlan, nil
+	case "plan-work":
+		return state.ModePlanWork, nil
+	default:
+		return "", fmt.Errorf("invalid mode '%s'. Must be: build, plan, or plan-work", value)
+	}
+}
+
+// parseRunner converts a --runner value.
+func parseRunner(value string) (state.Runner, error) {
+	switch value {
+	case "native":
+		return state.RunnerNative, nil
+	case "script":
+		return state.RunnerScript, nil
+	default:
+		return "", fmt.Errorf("invalid runner '%s'. Must be: native or script", value)
+	}
+}
+
+// parseLoopSpec parses a --loop value of comma-separated key=value pairs.
+// Keys not given are taken from base; work takes the rest of the value so
+// the description may contain commas.
+func parseLoopSpec(value string, base loopSpec) (loopSpec, error) {
+	spec := base
+	spec.name = ""
+	spec.workDesc = ""
+
+	rest := value
+	for rest != "" {
+		var pair string
+		if strings.HasPrefix(rest, "work=") {
+			pair, rest = rest, ""
+		} else {
+			pair, rest, _ = strings.Cut(rest, ",")
+		}
+
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok {
+			return loopSpec{}, fmt.Errorf("expected key=value, got '%s'", pair)
+		}
+		switch key {
+		case "name":
+			spec.name = val
+		case "mode":
+			mode, err := parseMode(val)
+			if err != nil {
+				return loopSpec{}, err
+			}
+			spec.mode = mode
+		case "max":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 0 {
+				return loopSpec{}, fmt.Errorf("max must be a non-negative number")
+			}
+			spec.maxIter = n
+		case "dir":
+			spec.dir = val
+		case "runner":
+			runner, err := parseRunner(val)
+			if err != nil {
+				return loopSpec{}, err
+			}
+			spec.runner = runner
+		case "work":
+			spec.workDesc = val
+		default:
+			return loopSpec{}, fmt.Errorf("unknown key '%s'", key)
+		}
+	}
+
+	// Guard: Every loop needs a name, and plan-work a description
+	if spec.name == "" {
+		return loopSpec{}, fmt.Errorf("name is required")
+	}
+	if spec.mode == state.ModePlanWork && spec.workDesc == "" {
+		return loopSpec{}, fmt.Errorf("plan-work mode requires work=")
+	}
+	return spec, nil
+}
+
+// checkLoopSpecs rejects duplicate names and loops sharing a checkout, which
+// would fight over sentinel files, commits and pushes.
+func checkLoopSpecs(specs []loopSpec) error {
+	names := make(map[string]bool)
+	dirs := make(map[string]string)
+	for _, spec := range specs {
+		if names[spec.name] {
+			return fmt.Errorf("duplicate loop name '%s'", spec.name)
+		}
+		names[spec.name] = true
+
+		dir, err := filepath.Abs(spec.dir)
+		if err != nil {
+			return fmt.Errorf("loop %s: %w", spec.name, err)
+		}
+		if other, ok := dirs[dir]; ok {
+			return fmt.Errorf("loops '%s' and '%s' share %s; give each its own checkout with dir=", other, spec.name, dir)
+		}
+		dirs[dir] = spec.name
+	}
+	return nil
+}
+
+// createWorktree checks out the loop's own worktree on ralph/<name> from the
+// repository at its dir and runs the loop there.
+func (spec *loopSpec) createWorktree() error {
+	repo, err := worktree.Open(spec.dir)
+	if err != nil {
+		return err
+	}
+	wt, err := repo.Create(worktree.Slugify(spec.name))
+	if err != nil {
+		return err
+	}
+	spec.worktree = &wt
+	spec.dir = wt.Path
+	return nil
+}
+
+// logBase returns the directory a relative --log-dir is resolved against.
+// Worktree loops log into the main checkout so logs outlive the worktree.
+func (spec loopSpec) logBase() string {
+	if spec.worktree != nil {
+		return spec.worktree.Root
+	}
+	return spec.dir
+}
@@ -4,19 +4,75 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/alex/ralph-tui/src/lib/attach"
+	"github.com/alex/ralph-tui/src/lib/loop"
 	"github.com/alex/ralph-tui/src/lib/process"
 	"github.com/alex/ralph-tui/src/lib/state"
+	"github.com/alex/ralph-tui/src/lib/worktree"
 	"github.com/alex/ralph-tui/src/tui"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+// stringList collects the values of a repeatable flag.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
 func main() {
+	// Reconnect to a daemon started with --daemon
+	if len(os.Args) > 1 && os.Args[1] == "attach" {
+		attachMain(os.Args[2:])
+		return
+	}
+
 	// Parse CLI flags
 	mode := flag.String("mode", "build", "Loop mode: build, plan, plan-work")
 	maxIter := flag.Int("max", 0, "Max iterations (0 = unlimited)")
 	workDesc := flag.String("work", "", "Work description for plan-work mode")
 	scriptPath := flag.String("script", "./loop.sh", "Path to loop.sh script")
+	runner := flag.String("runner", "native", "Loop runner: native (Go engine) or script (loop.sh)")
+	usePTY := flag.Bool("pty", false, "Run the loop under a pseudo-terminal to keep agent colors and progress output")
+	mergeStreams := flag.Bool("merge-streams", false, "Send stderr down the stdout pipe to keep exact output order (lines are no longer labelled as stderr)")
+	restart := flag.String("restart", "never", "Restart a crashed loop: never, on-failure, always")
+	maxRestarts := flag.Int("max-restarts", 5, "Max automatic restarts per run (0 = unlimited)")
+	restartBackoff := flag.Duration("restart-backoff", loop.DefaultRestartBackoff, "Delay before the first restart, doubled for each consecutive one")
+	iterationTimeout := flag.Duration("iteration-timeout", 0, "Kill an iteration running longer than this (0 = no limit)")
+	onTimeout := flag.String("on-timeout", "continue", "After an iteration times out: continue or stop")
+	stallTimeout := flag.Duration("stall-timeout", 0, "Warn when the agent prints nothing for this long (0 = off)")
+	onStall := flag.String("on-stall", "warn", "When the agent stalls: warn, interrupt (move to the next iteration) or stop")
+	stopSignals := flag.String("stop-signals", process.DefaultStopEscalation.String(), "Signal ladder for a graceful stop (x), e.g. TERM:30s,KILL")
+	interruptSignals := flag.String("interrupt-signals", process.DefaultInterruptEscalation.String(), "Signal ladder for an immediate stop (X)")
+	timeoutSignals := flag.String("timeout-signals", process.DefaultTimeoutEscalation.String(), "Signal ladder for a timed-out or stalled iteration")
+	workDir := flag.String("dir", "", "Run the loop in this directory (another checkout) instead of the current one")
+	var envSet, envUnset, extraArgs stringList
+	flag.Var(&envSet, "env", "Set NAME=value in the loop's environment (repeatable)")
+	flag.Var(&envUnset, "unset-env", "Remove NAME from the loop's environment (repeatable)")
+	flag.Var(&extraArgs, "arg", "Extra argument appended to the agent or loop.sh command (repeatable)")
+	logDir := flag.String("log-dir", process.DefaultLogDir, "Directory for per-session log files, relative to --dir")
+	noLog := flag.Bool("no-log", false, "Do not write session logs to disk")
+	logMaxSize := flag.Int64("log-max-size", process.DefaultLogMaxSize>>20, "Rotate a session log past this many MiB (0 = never)")
+	logMaxAge := flag.Duration("log-max-age", process.DefaultLogMaxAge, "Rotate a session log after this long (0 = never)")
+	logRetain := flag.Duration("log-retain", process.DefaultLogRetain, "Delete session logs not written to for this long (0 = forever)")
+	logKeep := flag.Int("log-keep", process.DefaultLogKeep, "Keep at most this many session logs (0 = unlimited)")
+	listSessions := flag.Bool("sessions", false, "List past session logs and exit")
+	showSession := flag.String("show-session", "", "Print a past session log (name or 'latest') and exit")
+	maxLine := flag.Int("max-line", process.DefaultMaxLineLength, "Longest output line in bytes kept as one log entry")
+	longLines := flag.String("long-lines", "truncate", "Lines past --max-line: truncate (note the elided bytes) or split (continuation entries)")
+	loopName := flag.String("name", state.DefaultLoopName, "Name of the loop configured by the flags above")
+	var extraLoops stringList
+	flag.Var(&extraLoops, "loop", "Run another loop alongside: name=NAME,mode=MODE,max=N,dir=DIR,runner=RUNNER,work=DESC (repeatable; unset keys inherit the flags above, work must come last)")
+	useWorktrees := flag.Bool("worktrees", false, "Run each loop in its own git worktree on branch ralph/<name> under .ralph/worktrees")
+	daemon := flag.Bool("daemon", false, "Run the loops in a background daemon and attach to it; quitting offers to detach (reattach with: ralph-tui attach)")
+	socket := flag.String("socket", "", "Daemon socket (default <dir>/"+attach.DefaultSocket+")")
+	orphans := flag.String("orphans", orphansAsk, "A loop left running by a ralph-tui that died: ask, adopt (monitor and control it) or kill")
 	flag.Parse()
 
 	// Guard: Validate max iterations is non-negative
@@ -26,41 +82,390 @@ func main() {
 	}
 
 	// Guard: Validate mode
-	var stateMode state.Mode
-	switch *mode {
-	case "build":
-		stateMode = state.ModeBuild
-	case "plan":
-		stateMode = state.ModePlan
-	case "plan-work":
-		// Guard: plan-work requires work description
-		if *workDesc == "" {
-			fmt.Fprintln(os.Stderr, "Error: plan-work mode requires --work flag")
+	stateMode, err := parseMode(*mode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	// Guard: plan-work requires work description
+	if stateMode == state.ModePlanWork && *workDesc == "" {
+		fmt.Fprintln(os.Stderr, "Error: plan-work mode requires --work flag")
+		os.Exit(1)
+	}
+
+	// Guard: Validate runner
+	stateRunner, err := parseRunner(*runner)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Guard: Validate restart policy
+	var restartMode state.RestartMode
+	switch *restart {
+	case "never":
+		restartMode = state.RestartNever
+	case "on-failure":
+		restartMode = state.RestartOnFailure
+	case "always":
+		restartMode = state.RestartAlways
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid restart policy '%s'. Must be: never, on-failure, or always\n", *restart)
+		os.Exit(1)
+	}
+	if *maxRestarts < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --max-restarts must be non-negative")
+		os.Exit(1)
+	}
+	if *restartBackoff <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --restart-backoff must be positive")
+		os.Exit(1)
+	}
+
+	// Guard: Validate iteration timeout
+	if *iterationTimeout < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --iteration-timeout must be non-negative")
+		os.Exit(1)
+	}
+	var timeoutAction state.TimeoutAction
+	switch *onTimeout {
+	case "continue":
+		timeoutAction = state.TimeoutContinue
+	case "stop":
+		timeoutAction = state.TimeoutStop
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid timeout action '%s'. Must be: continue or stop\n", *onTimeout)
+		os.Exit(1)
+	}
+
+	// Guard: Validate stall detection
+	if *stallTimeout < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --stall-timeout must be non-negative")
+		os.Exit(1)
+	}
+	var stallAction state.StallAction
+	switch *onStall {
+	case "warn":
+		stallAction = state.StallWarn
+	case "interrupt":
+		stallAction = state.StallInterrupt
+	case "stop":
+		stallAction = state.StallStop
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid stall action '%s'. Must be: warn, interrupt, or stop\n", *onStall)
+		os.Exit(1)
+	}
+
+	// Guard: Validate signal ladders
+	var escalation process.EscalationConfig
+	for _, ladder := range []struct {
+		flag   string
+		value  string
+		policy *process.EscalationPolicy
+	}{
+		{"--stop-signals", *stopSignals, &escalation.Stop},
+		{"--interrupt-signals", *interruptSignals, &escalation.Interrupt},
+		{"--timeout-signals", *timeoutSignals, &escalation.Timeout},
+	} {
+		policy, err := process.ParseEscalation(ladder.value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid %s '%s': %v\n", ladder.flag, ladder.value, err)
 			os.Exit(1)
 		}
-		stateMode = state.ModePlanWork
+		*ladder.policy = policy
+	}
+
+	// Guard: Validate start options
+	for _, entry := range envSet {
+		if name, _, ok := strings.Cut(entry, "="); !ok || name == "" {
+			fmt.Fprintf(os.Stderr, "Error: invalid --env '%s'. Must be NAME=value\n", entry)
+			os.Exit(1)
+		}
+	}
+	if *workDir != "" {
+		if info, err := os.Stat(*workDir); err != nil || !info.IsDir() {
+			fmt.Fprintf(os.Stderr, "Error: --dir '%s' is not a directory\n", *workDir)
+			os.Exit(1)
+		}
+	}
+
+	// Guard: Validate session logging
+	if *logMaxSize < 0 || *logMaxAge < 0 || *logRetain < 0 || *logKeep < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --log-max-size, --log-max-age, --log-retain and --log-keep must be non-negative")
+		os.Exit(1)
+	}
+	mainLogDir := *logDir
+	if !filepath.IsAbs(mainLogDir) {
+		mainLogDir = filepath.Join(*workDir, mainLogDir)
+	}
+
+	// Guard: Validate long line handling
+	if *maxLine <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --max-line must be positive")
+		os.Exit(1)
+	}
+	var longLinePolicy process.LongLinePolicy
+	switch *longLines {
+	case "truncate":
+		longLinePolicy = process.LongLineTruncate
+	case "split":
+		longLinePolicy = process.LongLineSplit
 	default:
-		fmt.Fprintf(os.Stderr, "Error: invalid mode '%s'. Must be: build, plan, or plan-work\n", *mode)
+		fmt.Fprintf(os.Stderr, "Error: invalid long line handling '%s'. Must be: truncate or split\n", *longLines)
+		os.Exit(1)
+	}
+
+	socketPath := *socket
+	if socketPath == "" {
+		socketPath = filepath.Join(*workDir, attach.DefaultSocket)
+	}
+
+	// Browse past sessions without starting the TUI
+	if *listSessions {
+		if err := printSessions(mainLogDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *showSession != "" {
+		if err := printSession(mainLogDir, *showSession); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Guard: Validate the loops to run; extra loops inherit the top-level flags
+	specs := []loopSpec{{
+		name:     *loopName,
+		mode:     stateMode,
+		maxIter:  *maxIter,
+		workDesc: *workDesc,
+		dir:      *workDir,
+		runner:   stateRunner,
+	}}
+	for _, raw := range extraLoops {
+		spec, err := parseLoopSpec(raw, specs[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --loop '%s': %v\n", raw, err)
+			os.Exit(1)
+		}
+		if info, err := os.Stat(spec.dir); spec.dir != "" && (err != nil || !info.IsDir()) {
+			fmt.Fprintf(os.Stderr, "Error: loop %s: '%s' is not a directory\n", spec.name, spec.dir)
+			os.Exit(1)
+		}
+		specs = append(specs, spec)
+	}
+	// Give every loop its own checkout; their logs stay in the main one
+	if *useWorktrees {
+		for i := range specs {
+			if err := specs[i].createWorktree(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: loop %s: %v\n", specs[i].name, err)
+				os.Exit(1)
+			}
+		}
+	}
+	if err := checkLoopSpecs(specs); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Initialize state and process manager
-	appState := state.NewState()
-	appState.SetMode(stateMode)
-	appState.SetMaxIterations(*maxIter)
-	appState.SetScriptPath(*scriptPath)
-	if *workDesc != "" {
-		appState.SetWorkDesc(*workDesc)
+	// Guard: A loop left running by a ralph-tui that died is adopted or
+	// killed instead of being started a second time
+	if *orphans != orphansAsk && *orphans != orphansAdopt && *orphans != orphansKill {
+		fmt.Fprintf(os.Stderr, "Error: invalid --orphans '%s'. Must be: ask, adopt, or kill\n", *orphans)
+		os.Exit(1)
+	}
+	adopt := make([]*process.PidFile, len(specs))
+	adopting := false
+	for i, spec := range specs {
+		orphan, err := resolveOrphan(spec, *orphans, escalation.Stop)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		adopt[i] = orphan
+		adopting = adopting || orphan != nil
 	}
 
-	manager := process.NewManager(1000)
+	// closers release each loop's scrollback and session log on exit
+	var closers []func()
+	closeAll := func() {
+		for _, release := range closers {
+			release()
+		}
+	}
 
-	// Create and run TUI
-	model := tui.NewModel(appState, manager)
-	program := tea.NewProgram(model, tea.WithAltScreen())
+	// With --daemon this process starts a background copy of itself that
+	// owns the loops, then attaches to it
+	var listener *attach.Listener
+	if *daemon {
+		if os.Getenv(daemonEnv) == "" {
+			// The daemon adopts what the user chose to adopt here
+			var extraArgs []string
+			if adopting {
+				extraArgs = append(extraArgs, "--orphans="+orphansAdopt)
+			}
+			if err := startDaemon(socketPath, extraArgs...); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := attachTo(socketPath); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+		// Keep the marker out of the loops' environment
+		os.Unsetenv(daemonEnv)
+		listener, err = attach.Listen(socketPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		closers = append(closers, func() { listener.Close() })
+	}
+
+	// newLoop creates the state and engine of one loop
+	newLoop := func(spec loopSpec) (*state.State, *loop.Engine, error) {
+		appState := state.NewState()
+		appState.SetName(spec.name)
+		appState.SetMode(spec.mode)
+		appState.SetMaxIterations(spec.maxIter)
+		appState.SetScriptPath(*scriptPath)
+		appState.SetRunner(spec.runner)
+		appState.SetRestartPolicy(state.RestartPolicy{
+			Mode:        restartMode,
+			MaxRestarts: *maxRestarts,
+			Backoff:     *restartBackoff,
+			MaxBackoff:  max(loop.DefaultMaxRestartBackoff, *restartBackoff),
+		})
+		appState.SetTimeoutPolicy(state.TimeoutPolicy{
+			Limit:  *iterationTimeout,
+			Action: timeoutAction,
+		})
+		appState.SetStallPolicy(state.StallPolicy{
+			Quiet:  *stallTimeout,
+			Action: stallAction,
+		})
+		appState.SetStartOptions(process.StartOptions{
+			Env:       envSet,
+			Unset:     envUnset,
+			Dir:       spec.dir,
+			ExtraArgs: extraArgs,
+		})
+		if spec.workDesc != "" {
+			appState.SetWorkDesc(spec.workDesc)
+		}
+		appState.SetWorktree(spec.worktree)
+		appState.SetEscalation(escalation)
+
+		manager := process.NewManager(1000)
+		manager.SetPTY(*usePTY)
+		manager.SetMergeStreams(*mergeStreams)
+		manager.SetLineLimit(*maxLine, longLinePolicy)
+		engine := loop.NewEngine(manager)
+
+		// Page output older than the in-memory lines out to disk so the logs
+		// view can scroll back through the whole session
+		scrollback, err := process.NewSpillBuffer(1000, "")
+		if err != nil {
+			return nil, nil, err
+		}
+		manager.SetLogStore(scrollback)
+		closers = append(closers, func() { scrollback.Close() })
+
+		// Mirror everything the manager captures into this session's log file
+		if !*noLog {
+			dir := *logDir
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(spec.logBase(), dir)
+			}
+			cfg := process.SessionLogConfig{
+				Dir:     dir,
+				MaxSize: *logMaxSize << 20,
+				MaxAge:  *logMaxAge,
+				Retain:  *logRetain,
+				Keep:    *logKeep,
+			}
+			// Loops may share a log directory; tell their sessions apart
+			if len(specs) > 1 {
+				cfg.Label = worktree.Slugify(spec.name)
+			}
+			sessionLog, err := process.OpenSessionLog(cfg)
+			if err != nil {
+				return nil, nil, err
+			}
+			manager.SetSessionLog(sessionLog)
+			appState.SetLogDir(dir)
+			closers = append(closers, func() { sessionLog.Close() })
+		}
+
+		return appState, engine, nil
+	}
 
-	if _, err := program.Run(); err != nil {
+	// Create and run TUI
+	var model *tui.Model
+	for i, spec := range specs {
+		appState, engine, err := newLoop(spec)
+		if err == nil && adopt[i] != nil {
+			err = engine.Adopt(tui.LoopConfig(appState), *adopt[i])
+		}
+		if err != nil {
+			closeAll()
+			fmt.Fprintf(os.Stderr, "Error: loop %s: %v\n", spec.name, err)
+			os.Exit(1)
+		}
+		if model == nil {
+			model = tui.NewModel(appState, engine)
+		} else {
+			model.AddLoop(appState, engine)
+		}
+	}
+	if listener != nil {
+		err = tui.Serve(model, listener)
+	} else {
+		program := tea.NewProgram(model, tea.WithAltScreen())
+		_, err = program.Run()
+	}
+	closeAll()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
+
+// printSessions lists the session logs in dir, newest first.
+func printSessions(dir string) error {
+	sessions, err := process.ListSessions(dir)
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		fmt.Printf("No session logs in %s\n", dir)
+		return nil
+	}
+	for _, session := range sessions {
+		fmt.Printf("%s  %10d bytes  %d part(s)  last written %s\n",
+			session.Name, session.Size, len(session.Files), session.UpdatedAt.Format("2006-01-02 15:04:05"))
+	}
+	return nil
+}
+
+// printSession writes a session log to stdout with timestamps and stream
+// labels.
+func printSession(dir, name string) error {
+	session, err := process.FindSession(dir, name)
+	if err != nil {
+		return err
+	}
+	entries, err := process.ReadSession(session)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		fmt.Printf("%s [%s] %s\n", entry.Time.Format("2006-01-02 15:04:05"), entry.Stream, entry.Text)
+	}
+	return nil
+}
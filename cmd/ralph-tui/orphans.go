@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/alex/ralph-tui/src/lib/loop"
+	"github.com/alex/ralph-tui/src/lib/process"
+	"github.com/alex/ralph-tui/src/lib/state"
+)
+
+// Ways of dealing with a loop process whose ralph-tui died (--orphans).
+const (
+	orphansAsk   = "ask"
+	orphansAdopt = "adopt"
+	orphansKill  = "kill"
+)
+
+// pidFilePath returns where the loop records its running process.
+func (s loopSpec) pidFilePath() string {
+	return loop.Config{Start: process.StartOptions{Dir: s.dir}}.PidFilePath()
+}
+
+// resolveOrphan checks the loop's pidfile for a process left running by a
+// ralph-tui that died, and kills it or returns it for adoption according to
+// action. A loop still owned by a live ralph-tui is an error; a stale
+// pidfile is removed.
+func resolveOrphan(spec loopSpec, action string, policy process.EscalationPolicy) (*process.PidFile, error) {
+	path := spec.pidFilePath()
+	orphan, err := process.ReadPidFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil || !orphan.Running() {
+		_ = os.Remove(path)
+		return nil, nil
+	}
+
+	// Guard: Never run a loop twice
+	if orphan.OwnerRunning() {
+		return nil, fmt.Errorf("loop %s is already running under ralph-tui %d (pid %d); if that is a daemon, attach with: ralph-tui attach", spec.name, orphan.Owner, orphan.PID)
+	}
+
+	description := fmt.Sprintf("%s loop running %s (pid %d, iteration %d, started %s ago)",
+		orphan.Mode, orphan.Script, orphan.PID, orphan.Iteration, orphan.Started())
+	if action == orphansAsk {
+		if action, err = askOrphan(spec.name, orphan.Runner, description); err != nil {
+			return nil, err
+		}
+	}
+
+	if action == orphansKill {
+		if err := process.KillOrphan(orphan, policy); err != nil {
+			return nil, fmt.Errorf("failed to kill orphaned loop %s: %w", spec.name, err)
+		}
+		fmt.Printf("Killed orphaned %s\n", description)
+		_ = os.Remove(path)
+		return nil, nil
+	}
+	return &orphan, nil
+}
+
+// askOrphan asks on the terminal whether to adopt or kill an orphan.
+func askOrphan(name, runner, description string) (string, error) {
+	// Guard: Nobody to ask
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return "", fmt.Errorf("loop %s: found an orphaned %s; rerun with --orphans=adopt or --orphans=kill", name, description)
+	}
+
+	fmt.Printf("Loop %s: found an orphaned %s, left behind by a ralph-tui that exited.\n", name, description)
+	if runner == string(state.RunnerNative) {
+		fmt.Println("It runs a single agent: adopting only watches that agent; the next iteration will not be started and its output is not captured.")
+	}
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Print("Adopt it (monitor and control it), kill it, or quit? [a/k/q] ")
+		answer, err := reader.ReadString('\n')
+		if err != nil {
+			return "", fmt.Errorf("no answer for orphaned loop %s", name)
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "a", "adopt":
+			return orphansAdopt, nil
+		case "k", "kill":
+			return orphansKill, nil
+		case "q", "quit":
+			return "", fmt.Errorf("left orphaned loop %s running", name)
+		}
+	}
+}
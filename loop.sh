@@ -45,8 +45,27 @@ fi
 ITERATION=0
 CURRENT_BRANCH=$(git branch --show-current)
 
+# Pause sentinel: while this file exists the loop waits before starting the
+# next iteration (created/removed by ralph-tui's pause/resume, which exports
+# its path as RALPH_PAUSE_FILE)
+PAUSE_FILE="${RALPH_PAUSE_FILE:-.ralph/pause}"
+
+# Stop sentinel: if this file exists the loop exits cleanly before starting
+# the next iteration (created by ralph-tui's stop-after-iteration, which
+# exports its path as RALPH_STOP_FILE)
+STOP_FILE="${RALPH_STOP_FILE:-.ralph/stop}"
+
+# Output copy: a ralph-tui that adopts this loop after the one that started
+# it died follows RALPH_OUTPUT_FILE. One tee takes both streams so the file
+# keeps their order; it ignores SIGPIPE so it keeps writing the file once the
+# pipe to the dead ralph-tui is gone. Skipped on a terminal, where it would
+# cost the agent its colors.
+if [ -n "${RALPH_OUTPUT_FILE:-}" ] && [ ! -t 1 ]; then
+    exec > >(trap '' PIPE; exec tee -a "$RALPH_OUTPUT_FILE") 2>&1
+fi
+
 # Model configuration (can be overridden via environment variable)
-MODEL="opencode/claude-opus-4-5"
+MODEL="${MODEL:-opencode/claude-opus-4-5}"
 
 # Validate branch for plan-work mode
 if [ "$MODE" = "plan-work" ]; then
@@ -69,7 +88,10 @@ if [ "$MODE" = "plan-work" ]; then
     # Warn about uncommitted changes to IMPLEMENTATION_PLAN.md
     if [ -f "IMPLEMENTATION_PLAN.md" ] && ! git diff --quiet IMPLEMENTATION_PLAN.md 2>/dev/null; then
         echo "Warning: IMPLEMENTATION_PLAN.md has uncommitted changes that will be overwritten"
-        read -p "Continue? [y/N] " -n 1 -r
+        # read -p only prints to a terminal; echo so the prompt shows when
+        # stdin is a pipe (e.g. answered from ralph-tui)
+        echo "Continue? [y/N]"
+        read -n 1 -r
         echo
         [[ ! $REPLY =~ ^[Yy]$ ]] && exit 1
     fi
@@ -110,6 +132,27 @@ while true; do
         break
     fi
 
+    # Finish here if asked to stop after the last iteration
+    if [ -f "$STOP_FILE" ]; then
+        rm -f "$STOP_FILE"
+        echo "STOPPED after iteration $ITERATION"
+        exit 0
+    fi
+
+    # Wait between iterations while paused
+    if [ -f "$PAUSE_FILE" ]; then
+        echo "PAUSED after iteration $ITERATION"
+        while [ -f "$PAUSE_FILE" ] && [ ! -f "$STOP_FILE" ]; do
+            sleep 1
+        done
+        if [ -f "$STOP_FILE" ]; then
+            rm -f "$STOP_FILE"
+            echo "STOPPED after iteration $ITERATION"
+            exit 0
+        fi
+        echo "RESUMED at iteration $((ITERATION + 1))"
+    fi
+
     # Run Ralph iteration with selected prompt using opencode
     # opencode run: Non-interactive mode for scripting/automation
     # --model: Model configuration to use
@@ -127,7 +170,7 @@ while true; do
 
     OUTPUT=$(opencode run "$PROMPT_CONTENT" \
         --model "$MODEL" \
-        --agent "build" 2>&1 | tee /dev/stderr) || true
+        --agent "build" < /dev/null 2>&1 | tee /dev/stderr) || true
 
     # Check for completion signal
     if echo "$OUTPUT" | grep -q "<promise>COMPLETE</promise>"; then
@@ -0,0 +1,295 @@
+// Package ansi prepares agent output for display: it keeps SGR (color and
+// text attribute) sequences, drops cursor movement, screen clearing and other
+// control sequences, and measures lines in terminal cells.
+package ansi
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	esc = '\x1b'
+
+	// tabWidth is the tab stop used when expanding tabs.
+	tabWidth = 8
+
+	// reset clears every SGR attribute.
+	reset = "\x1b[0m"
+)
+
+// Segment is a run of text sharing one SGR style.
+type Segment struct {
+	Style string // SGR parameters, e.g. "1;31" ("" = terminal default)
+	Text  string
+}
+
+// Line is a single log line split into styled segments. Every Line starts in
+// the default style and String() resets at its end, so styles never bleed
+// into the next line.
+type Line struct {
+	Segments []Segment
+}
+
+// Parse splits a raw line into styled segments. SGR sequences are applied to
+// the running style; all other escape and control sequences are removed.
+// A carriage return discards the text before it, like a terminal overwriting
+// a progress line.
+func Parse(s string) Line {
+	var (
+		line  Line
+		st    sgrState
+		text  strings.Builder
+		col   int
+		style string
+	)
+
+	flush := func() {
+		if text.Len() > 0 {
+			line.append(Segment{Style: style, Text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		switch {
+		case c == esc:
+			seq, final, n := scanEscape(s[i:])
+			if final == 'm' && !isPrivate(seq) {
+				flush()
+				st.apply(seq)
+				style = st.String()
+			}
+			i += n
+
+		case c == '\r':
+			// Overwrite semantics: keep the style, drop the text so far
+			text.Reset()
+			line.Segments = nil
+			col = 0
+			i++
+
+		case c == '\t':
+			spaces := tabWidth - col%tabWidth
+			text.WriteString(strings.Repeat(" ", spaces))
+			col += spaces
+			i++
+
+		case c < 0x20 || c == 0x7f:
+			// Other C0 controls (bell, backspace, ...) have no place in a log
+			i++
+
+		default:
+			r, size := utf8.DecodeRuneInString(s[i:])
+			text.WriteString(s[i : i+size])
+			col += runewidth.RuneWidth(r)
+			i += size
+		}
+	}
+	flush()
+
+	return line
+}
+
+// Sanitize is shorthand for Parse(s).String().
+func Sanitize(s string) string {
+	return Parse(s).String()
+}
+
+// Strip returns s with all escape and control sequences removed.
+func Strip(s string) string {
+	return Parse(s).Plain()
+}
+
+// append adds a segment, merging it with the previous one if styles match.
+func (l *Line) append(seg Segment) {
+	if seg.Text == "" {
+		return
+	}
+	if n := len(l.Segments); n > 0 && l.Segments[n-1].Style == seg.Style {
+		l.Segments[n-1].Text += seg.Text
+		return
+	}
+	l.Segments = append(l.Segments, seg)
+}
+
+// Prepend returns the line with text in the given SGR style in front of it.
+func (l Line) Prepend(style, text string) Line {
+	var result Line
+	result.append(Segment{Style: style, Text: text})
+	for _, seg := range l.Segments {
+		result.append(seg)
+	}
+	return result
+}
+
+// String renders the line with normalized SGR sequences, ending in the
+// default style.
+func (l Line) String() string {
+	var b strings.Builder
+	current := ""
+
+	for _, seg := range l.Segments {
+		if seg.Style != current {
+			if current != "" {
+				b.WriteString(reset)
+			}
+			if seg.Style != "" {
+				b.WriteString("\x1b[" + seg.Style + "m")
+			}
+			current = seg.Style
+		}
+		b.WriteString(seg.Text)
+	}
+
+	if current != "" {
+		b.WriteString(reset)
+	}
+
+	return b.String()
+}
+
+// Plain returns the visible text without any styling.
+func (l Line) Plain() string {
+	var b strings.Builder
+	for _, seg := range l.Segments {
+		b.WriteString(seg.Text)
+	}
+	return b.String()
+}
+
+// Width returns the number of terminal cells the line occupies.
+func (l Line) Width() int {
+	width := 0
+	for _, seg := range l.Segments {
+		width += runewidth.StringWidth(seg.Text)
+	}
+	return width
+}
+
+// Truncate shortens the line to at most width cells. If the line is cut,
+// tail (unstyled) is appended within the width.
+func (l Line) Truncate(width int, tail string) Line {
+	if l.Width() <= width {
+		return l
+	}
+
+	tailWidth := runewidth.StringWidth(tail)
+	if tailWidth > width {
+		tail, tailWidth = "", 0
+	}
+
+	head, _ := l.split(width - tailWidth)
+	head.append(Segment{Text: tail})
+	return head
+}
+
+// Wrap breaks the line into lines of at most width cells. Styles carry over
+// to continuation lines.
+func (l Line) Wrap(width int) []Line {
+	// Guard: Nothing sensible to do without room for a single cell
+	if width <= 0 {
+		return []Line{l}
+	}
+
+	var lines []Line
+	rest := l
+	for rest.Width() > width {
+		var head Line
+		head, rest = rest.split(width)
+		if len(head.Segments) == 0 {
+			// A wide rune that cannot fit at all; emit it on its own
+			head, rest = rest.split(runewidth.RuneWidth(firstRune(rest)))
+		}
+		lines = append(lines, head)
+	}
+	if len(rest.Segments) > 0 || len(lines) == 0 {
+		lines = append(lines, rest)
+	}
+	return lines
+}
+
+// split cuts the line after at most width cells.
+func (l Line) split(width int) (head, rest Line) {
+	used := 0
+	for i, seg := range l.Segments {
+		for j, r := range seg.Text {
+			w := runewidth.RuneWidth(r)
+			if used+w > width {
+				head.append(Segment{Style: seg.Style, Text: seg.Text[:j]})
+				rest.append(Segment{Style: seg.Style, Text: seg.Text[j:]})
+				for _, after := range l.Segments[i+1:] {
+					rest.append(after)
+				}
+				return head, rest
+			}
+			used += w
+		}
+		head.append(seg)
+	}
+	return head, rest
+}
+
+// firstRune returns the first visible rune of the line.
+func firstRune(l Line) rune {
+	for _, seg := range l.Segments {
+		for _, r := range seg.Text {
+			return r
+		}
+	}
+	return 0
+}
+
+// isPrivate reports whether CSI parameters use a private prefix (e.g. the
+// "\x1b[>4;2m" key modifier setting), which makes them not SGR.
+func isPrivate(params string) bool {
+	return params != "" && strings.ContainsRune("<=>?", rune(params[0]))
+}
+
+// scanEscape measures the escape sequence at the start of s. For CSI
+// sequences it returns the parameter bytes and final byte; other sequences
+// (OSC, DCS, charset selection, ...) return final 0. n is the number of
+// bytes consumed, including unterminated sequences at the end of s.
+func scanEscape(s string) (params string, final byte, n int) {
+	if len(s) < 2 {
+		return "", 0, len(s)
+	}
+
+	switch s[1] {
+	case '[':
+		// CSI: parameter and intermediate bytes, then a final byte in 0x40-0x7e
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return s[2:i], s[i], i + 1
+			}
+		}
+		return "", 0, len(s)
+
+	case ']', 'P', 'X', '^', '_':
+		// String sequences end with BEL or ST (ESC \)
+		for i := 2; i < len(s); i++ {
+			if s[i] == '\a' {
+				return "", 0, i + 1
+			}
+			if s[i] == esc && i+1 < len(s) && s[i+1] == '\\' {
+				return "", 0, i + 2
+			}
+		}
+		return "", 0, len(s)
+
+	case '(', ')', '*', '+', '#', '%':
+		// Charset designation and similar: one more byte
+		if len(s) < 3 {
+			return "", 0, len(s)
+		}
+		return "", 0, 3
+
+	default:
+		// Two-byte sequences such as ESC 7 / ESC 8 / ESC =
+		return "", 0, 2
+	}
+}
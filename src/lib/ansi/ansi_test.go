@@ -0,0 +1,152 @@
+package ansi
+
+import (
+	"testing"
+)
+
+func TestParse_KeepsColorsAndResetsAtEnd(t *testing.T) {
+	got := Sanitize("\x1b[1;31merror:\x1b[0m details \x1b[32mok")
+
+	want := "\x1b[1;31merror:\x1b[0m details \x1b[32mok\x1b[0m"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestParse_StripsCursorAndClearSequences(t *testing.T) {
+	input := "\x1b[2K\x1b[1Gspinner \x1b[?25l\x1b[3Adone\x1b]0;title\x07\x1b(B"
+
+	got := Sanitize(input)
+	if got != "spinner done" {
+		t.Errorf("expected control sequences removed, got %q", got)
+	}
+}
+
+func TestParse_CarriageReturnOverwrites(t *testing.T) {
+	got := Parse("\x1b[33m 10%\r 50%\r100%").String()
+
+	want := "\x1b[33m100%\x1b[0m"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestParse_ExpandsTabs(t *testing.T) {
+	got := Strip("a\tb")
+	if got != "a       b" {
+		t.Errorf("expected tab expanded to column 8, got %q", got)
+	}
+}
+
+func TestParse_NormalizesRedundantSGR(t *testing.T) {
+	// Consecutive color changes should not accumulate parameters
+	line := Parse("\x1b[31m\x1b[32m\x1b[1mtext\x1b[22;39mplain")
+
+	if len(line.Segments) != 2 {
+		t.Fatalf("expected 2 segments, got %+v", line.Segments)
+	}
+	if line.Segments[0].Style != "1;32" {
+		t.Errorf("expected style 1;32, got %q", line.Segments[0].Style)
+	}
+	if line.Segments[1].Style != "" {
+		t.Errorf("expected default style after reset codes, got %q", line.Segments[1].Style)
+	}
+}
+
+func TestParse_ExtendedColors(t *testing.T) {
+	line := Parse("\x1b[38;5;208;48;2;1;2;3mx")
+
+	if line.Segments[0].Style != "38;5;208;48;2;1;2;3" {
+		t.Errorf("unexpected style %q", line.Segments[0].Style)
+	}
+}
+
+func TestParse_IgnoresPrivateModeM(t *testing.T) {
+	if got := Sanitize("\x1b[>4;2mtext"); got != "text" {
+		t.Errorf("expected private sequence dropped, got %q", got)
+	}
+}
+
+func TestParse_UnterminatedSequence(t *testing.T) {
+	if got := Strip("text\x1b[31"); got != "text" {
+		t.Errorf("expected dangling sequence dropped, got %q", got)
+	}
+}
+
+func TestLine_Width(t *testing.T) {
+	cases := map[string]int{
+		"":                       0,
+		"hello":                  5,
+		"\x1b[31mhello\x1b[0m":   5,
+		"日本":                     4,
+		"\x1b[1m✓\x1b[0m passed": 8,
+	}
+	for input, want := range cases {
+		if got := Parse(input).Width(); got != want {
+			t.Errorf("Width(%q) = %d, want %d", input, got, want)
+		}
+	}
+}
+
+func TestLine_Truncate(t *testing.T) {
+	line := Parse("\x1b[31mred text\x1b[0m plain")
+
+	got := line.Truncate(6, "…").String()
+	want := "\x1b[31mred t\x1b[0m…"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	// Short lines are untouched
+	if got := Parse("short").Truncate(10, "…").String(); got != "short" {
+		t.Errorf("expected short line untouched, got %q", got)
+	}
+}
+
+func TestLine_TruncateWideRunes(t *testing.T) {
+	// Never split a double-width rune across the limit
+	got := Parse("日本語").Truncate(4, "").Plain()
+	if got != "日本" {
+		t.Errorf("expected %q, got %q", "日本", got)
+	}
+
+	got = Parse("日本語").Truncate(3, "").Plain()
+	if got != "日" {
+		t.Errorf("expected %q, got %q", "日", got)
+	}
+}
+
+func TestLine_WrapCarriesStyle(t *testing.T) {
+	lines := Parse("\x1b[32mabcdefgh\x1b[0mij").Wrap(4)
+
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(lines))
+	}
+
+	want := []string{
+		"\x1b[32mabcd\x1b[0m",
+		"\x1b[32mefgh\x1b[0m",
+		"ij",
+	}
+	for i, line := range lines {
+		if got := line.String(); got != want[i] {
+			t.Errorf("line %d: expected %q, got %q", i, want[i], got)
+		}
+	}
+}
+
+func TestLine_WrapNarrowerThanRune(t *testing.T) {
+	lines := Parse("日本").Wrap(1)
+	if len(lines) != 2 {
+		t.Fatalf("expected each wide rune on its own line, got %d lines", len(lines))
+	}
+}
+
+func TestLine_Prepend(t *testing.T) {
+	got := Parse("\rbody").Prepend("2", "12:00:00 ").String()
+
+	want := "\x1b[2m12:00:00 \x1b[0mbody"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
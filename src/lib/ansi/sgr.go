@@ -0,0 +1,108 @@
+package ansi
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Text attribute SGR codes, indexed by code (1-9).
+const attrCount = 10
+
+// sgrState tracks the text attributes and colors set by SGR sequences.
+type sgrState struct {
+	attrs [attrCount]bool // attrs[1] bold, attrs[3] italic, attrs[4] underline, ...
+	fg    string          // Foreground parameters, e.g. "31" or "38;5;208"
+	bg    string          // Background parameters
+}
+
+// apply updates the state from the parameters of an SGR sequence.
+func (s *sgrState) apply(params string) {
+	// An empty parameter list means reset
+	if params == "" {
+		*s = sgrState{}
+		return
+	}
+
+	codes := strings.FieldsFunc(params, func(r rune) bool { return r == ';' || r == ':' })
+	if len(codes) == 0 {
+		*s = sgrState{}
+		return
+	}
+
+	for i := 0; i < len(codes); i++ {
+		code, err := strconv.Atoi(codes[i])
+		if err != nil {
+			continue
+		}
+
+		switch {
+		case code == 0:
+			*s = sgrState{}
+		case code >= 1 && code <= 9:
+			s.attrs[code] = true
+		case code == 22:
+			s.attrs[1] = false
+			s.attrs[2] = false
+		case code >= 23 && code <= 29:
+			s.attrs[code-20] = false
+		case (code >= 30 && code <= 37) || (code >= 90 && code <= 97):
+			s.fg = codes[i]
+		case code == 39:
+			s.fg = ""
+		case (code >= 40 && code <= 47) || (code >= 100 && code <= 107):
+			s.bg = codes[i]
+		case code == 49:
+			s.bg = ""
+		case code == 38 || code == 48:
+			color, consumed := extendedColor(codes[i+1:])
+			if color != "" {
+				if code == 38 {
+					s.fg = "38;" + color
+				} else {
+					s.bg = "48;" + color
+				}
+			}
+			i += consumed
+		}
+	}
+}
+
+// extendedColor parses the arguments of a 38/48 code: "5;n" or "2;r;g;b".
+// Returns the normalized parameters and the number of codes consumed.
+func extendedColor(codes []string) (string, int) {
+	if len(codes) == 0 {
+		return "", 0
+	}
+
+	switch codes[0] {
+	case "5":
+		if len(codes) < 2 {
+			return "", len(codes)
+		}
+		return "5;" + codes[1], 2
+	case "2":
+		if len(codes) < 4 {
+			return "", len(codes)
+		}
+		return "2;" + strings.Join(codes[1:4], ";"), 4
+	default:
+		return "", 1
+	}
+}
+
+// String returns the SGR parameters reproducing the state ("" = default).
+func (s sgrState) String() string {
+	var params []string
+	for code := 1; code < attrCount; code++ {
+		if s.attrs[code] {
+			params = append(params, strconv.Itoa(code))
+		}
+	}
+	if s.fg != "" {
+		params = append(params, s.fg)
+	}
+	if s.bg != "" {
+		params = append(params, s.bg)
+	}
+	return strings.Join(params, ";")
+}
@@ -0,0 +1,263 @@
+package attach
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultSocket is where the daemon listens, relative to the main checkout.
+const DefaultSocket = ".ralph/ralph-tui.sock"
+
+// Frame types sent by the client. The daemon's output is sent back as a
+// plain byte stream.
+const (
+	frameData   byte = 'd' // Terminal input
+	frameResize byte = 'r' // New terminal size: cols, rows as uint16
+)
+
+// maxFrame is the largest payload of one frame.
+const maxFrame = 1<<16 - 1
+
+// handshakeTimeout is how long a new client has to send its terminal size.
+const handshakeTimeout = 5 * time.Second
+
+// ErrNoDaemon is returned by Dial when nothing listens on the socket.
+var ErrNoDaemon = errors.New("no ralph-tui daemon running")
+
+// Size is a terminal size in cells.
+type Size struct {
+	Cols int
+	Rows int
+}
+
+// writeFrame sends one frame: type, big-endian uint16 length, payload.
+func writeFrame(w io.Writer, kind byte, payload []byte) error {
+	header := []byte{kind, 0, 0}
+	binary.BigEndian.PutUint16(header[1:], uint16(len(payload)))
+	if _, err := w.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// readFrame reads one frame written by writeFrame.
+func readFrame(r *bufio.Reader) (byte, []byte, error) {
+	var header [3]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(header[1:]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
+
+// sendResize tells the daemon the terminal's size.
+func sendResize(w io.Writer, size Size) error {
+	payload := make([]byte, 4)
+	binary.BigEndian.PutUint16(payload[0:], uint16(size.Cols))
+	binary.BigEndian.PutUint16(payload[2:], uint16(size.Rows))
+	return writeFrame(w, frameResize, payload)
+}
+
+// sendInput forwards terminal input, split into frames.
+func sendInput(w io.Writer, data []byte) error {
+	for len(data) > 0 {
+		n := min(len(data), maxFrame)
+		if err := writeFrame(w, frameData, data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// Listener accepts attach connections on a Unix socket. Close removes the
+// socket file.
+type Listener struct {
+	net.Listener
+	path string
+}
+
+// Listen creates the socket at path. A socket left behind by a daemon that
+// died is replaced; a live one is an error.
+func Listen(path string) (*Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	// Guard: Another daemon owns this socket
+	if Alive(path) {
+		return nil, fmt.Errorf("a ralph-tui daemon is already listening on %s", path)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	// Attaching gives full control of the loops; keep it to the owner
+	if err := os.Chmod(path, 0o600); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to restrict socket: %w", err)
+	}
+	return &Listener{Listener: ln, path: path}, nil
+}
+
+// AcceptSession waits for the next client to attach. Connections that close
+// before sending their terminal size, such as Alive's probes, are skipped.
+func (l *Listener) AcceptSession() (*Session, error) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		s := NewSession(conn)
+		select {
+		case <-s.attached:
+			return s, nil
+		case <-s.done:
+		case <-time.After(handshakeTimeout):
+		}
+		s.Close()
+	}
+}
+
+// Path returns the socket path.
+func (l *Listener) Path() string {
+	return l.path
+}
+
+// Alive reports whether a daemon is listening on the socket at path. The
+// probe connection is closed before any frame is sent.
+func Alive(path string) bool {
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// Dial connects to the daemon listening at path.
+func Dial(path string) (net.Conn, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) || errors.Is(err, syscall.ECONNREFUSED) {
+			return nil, fmt.Errorf("%w on %s", ErrNoDaemon, path)
+		}
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Session is the daemon's side of one attached client. Reads return the
+// client's terminal input and writes go to its terminal.
+type Session struct {
+	conn     net.Conn
+	input    *io.PipeReader
+	resizes  chan Size
+	attached chan struct{} // Closed on the first resize
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewSession starts reading frames from an accepted connection.
+func NewSession(conn net.Conn) *Session {
+	input, inputWriter := io.Pipe()
+	s := &Session{
+		conn:     conn,
+		input:    input,
+		resizes:  make(chan Size, 1),
+		attached: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go s.readFrames(inputWriter)
+	return s
+}
+
+// readFrames feeds input frames into the pipe and resize frames into the
+// resize channel until the client goes away.
+func (s *Session) readFrames(input *io.PipeWriter) {
+	defer close(s.done)
+	defer close(s.resizes)
+
+	reader := bufio.NewReader(s.conn)
+	attached := false
+	for {
+		kind, payload, err := readFrame(reader)
+		if err != nil {
+			input.CloseWithError(io.EOF)
+			return
+		}
+
+		switch kind {
+		case frameData:
+			if _, err := input.Write(payload); err != nil {
+				return
+			}
+		case frameResize:
+			if len(payload) != 4 {
+				continue
+			}
+			size := Size{
+				Cols: int(binary.BigEndian.Uint16(payload[0:])),
+				Rows: int(binary.BigEndian.Uint16(payload[2:])),
+			}
+			// Only the latest size matters
+			select {
+			case <-s.resizes:
+			default:
+			}
+			s.resizes <- size
+			if !attached {
+				attached = true
+				close(s.attached)
+			}
+		}
+	}
+}
+
+// Read returns terminal input from the client.
+func (s *Session) Read(p []byte) (int, error) {
+	return s.input.Read(p)
+}
+
+// Write sends output to the client's terminal.
+func (s *Session) Write(p []byte) (int, error) {
+	return s.conn.Write(p)
+}
+
+// Resizes delivers the client's terminal size whenever it changes. Closed
+// when the client goes away.
+func (s *Session) Resizes() <-chan Size {
+	return s.resizes
+}
+
+// Done is closed when the client disconnects.
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close disconnects the client.
+func (s *Session) Close() error {
+	var err error
+	s.once.Do(func() {
+		err = s.conn.Close()
+		s.input.Close()
+	})
+	return err
+}
@@ -0,0 +1,158 @@
+package attach
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// listenTemp listens on a socket in a temp dir.
+func listenTemp(t *testing.T) *Listener {
+	t.Helper()
+	ln, err := Listen(filepath.Join(t.TempDir(), "ralph.sock"))
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	return ln
+}
+
+// accept dials the listener and returns both ends.
+func accept(t *testing.T, ln *Listener) (net.Conn, *Session) {
+	t.Helper()
+	client, err := Dial(ln.Path())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+	session := NewSession(conn)
+	t.Cleanup(func() { session.Close() })
+	return client, session
+}
+
+func TestSession_InputAndResize(t *testing.T) {
+	ln := listenTemp(t)
+	client, session := accept(t, ln)
+
+	if err := sendResize(client, Size{Cols: 120, Rows: 40}); err != nil {
+		t.Fatalf("Failed to send resize: %v", err)
+	}
+	// Input larger than one frame arrives in one piece
+	input := bytes.Repeat([]byte("q"), maxFrame+10)
+	go sendInput(client, input)
+
+	select {
+	case size := <-session.Resizes():
+		if size != (Size{Cols: 120, Rows: 40}) {
+			t.Errorf("Expected 120x40, got %+v", size)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for resize")
+	}
+
+	got := make([]byte, len(input))
+	if _, err := io.ReadFull(session, got); err != nil {
+		t.Fatalf("Failed to read input: %v", err)
+	}
+	if !bytes.Equal(got, input) {
+		t.Error("Input was not forwarded intact")
+	}
+
+	// Output goes straight to the client
+	if _, err := session.Write([]byte("hello")); err != nil {
+		t.Fatalf("Failed to write output: %v", err)
+	}
+	out := make([]byte, 5)
+	if _, err := io.ReadFull(client, out); err != nil || string(out) != "hello" {
+		t.Errorf("Expected hello, got %q (%v)", out, err)
+	}
+}
+
+func TestSession_DoneOnDisconnect(t *testing.T) {
+	ln := listenTemp(t)
+	client, session := accept(t, ln)
+
+	client.Close()
+	select {
+	case <-session.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected session done after client disconnect")
+	}
+	if _, err := session.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected EOF after disconnect, got %v", err)
+	}
+}
+
+func TestListener_AcceptSessionSkipsProbes(t *testing.T) {
+	ln := listenTemp(t)
+
+	if !Alive(ln.Path()) {
+		t.Fatal("Expected live socket")
+	}
+	client, err := Dial(ln.Path())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer client.Close()
+	if err := sendResize(client, Size{Cols: 80, Rows: 24}); err != nil {
+		t.Fatalf("Failed to send resize: %v", err)
+	}
+
+	session, err := ln.AcceptSession()
+	if err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+	defer session.Close()
+	// The probe was skipped; the client's size is still delivered
+	if size := <-session.Resizes(); size != (Size{Cols: 80, Rows: 24}) {
+		t.Errorf("Expected 80x24, got %+v", size)
+	}
+}
+
+func TestListen_SocketOwnership(t *testing.T) {
+	ln := listenTemp(t)
+
+	if !Alive(ln.Path()) {
+		t.Error("Expected live socket")
+	}
+	// Guard: A second daemon cannot take over a live socket
+	if _, err := Listen(ln.Path()); err == nil {
+		t.Error("Expected error listening on a live socket")
+	}
+
+	ln.Close()
+	if _, err := os.Stat(ln.Path()); !os.IsNotExist(err) {
+		t.Errorf("Expected socket removed on close, got %v", err)
+	}
+	if _, err := Dial(ln.Path()); !errors.Is(err, ErrNoDaemon) {
+		t.Errorf("Expected ErrNoDaemon, got %v", err)
+	}
+}
+
+func TestListen_ReplacesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ralph.sock")
+
+	// A socket file nobody listens on, as left by a killed daemon
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Failed to create socket: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, err := Listen(path)
+	if err != nil {
+		t.Fatalf("Expected stale socket replaced, got %v", err)
+	}
+	ln.Close()
+}
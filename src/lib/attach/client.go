@@ -0,0 +1,77 @@
+package attach
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// restoreScreen leaves the alternate screen and shows the cursor, in case
+// the daemon went away before it could do so itself.
+const restoreScreen = "\x1b[?1049l\x1b[?25h"
+
+// Run attaches the terminal to the daemon on conn until either side hangs
+// up. Input is forwarded in raw mode; the terminal is restored on return.
+func Run(conn net.Conn, in, out *os.File) error {
+	restore, err := makeRaw(int(in.Fd()))
+	if err != nil {
+		return err
+	}
+	defer restore()
+	defer out.WriteString(restoreScreen)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	// Input and resizes are written from separate goroutines
+	var mu sync.Mutex
+	send := func(write func(io.Writer) error) {
+		mu.Lock()
+		defer mu.Unlock()
+		_ = write(conn)
+	}
+
+	resize := func() {
+		if size, err := termSize(int(out.Fd())); err == nil {
+			send(func(w io.Writer) error { return sendResize(w, size) })
+		}
+	}
+	resize()
+
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	defer signal.Stop(winch)
+	go func() {
+		for {
+			select {
+			case <-winch:
+				resize()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := in.Read(buf)
+			if n > 0 {
+				data := buf[:n]
+				send(func(w io.Writer) error { return sendInput(w, data) })
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	if _, err := io.Copy(out, conn); err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	return nil
+}
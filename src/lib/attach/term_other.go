@@ -0,0 +1,18 @@
+//go:build !linux
+
+package attach
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// makeRaw is only implemented on Linux.
+func makeRaw(fd int) (func(), error) {
+	return nil, fmt.Errorf("attach is not supported on %s", runtime.GOOS)
+}
+
+// termSize is only implemented on Linux.
+func termSize(fd int) (Size, error) {
+	return Size{}, fmt.Errorf("attach is not supported on %s", runtime.GOOS)
+}
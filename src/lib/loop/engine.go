@@ -0,0 +1,1119 @@
+package loop
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alex/ralph-tui/src/lib/process"
+	"github.com/alex/ralph-tui/src/lib/state"
+)
+
+const (
+	// DefaultAgentCommand is the agent binary invoked once per iteration.
+	DefaultAgentCommand = "opencode"
+
+	// DefaultModel matches the MODEL configured in loop.sh.
+	DefaultModel = "opencode/claude-opus-4-5"
+
+	// PromptPlaceholder is replaced by the prompt content in AgentArgs.
+	PromptPlaceholder = "{prompt}"
+
+	// CompletionMarker is printed by the agent when all tasks are done.
+	CompletionMarker = "<promise>COMPLETE</promise>"
+
+	// DefaultControlDir holds the sentinel files loop.sh checks between iterations.
+	DefaultControlDir = ".ralph"
+
+	// PauseFile is the sentinel that makes loop.sh wait before its next iteration.
+	PauseFile = "pause"
+
+	// StopFile is the sentinel that makes loop.sh exit before its next iteration.
+	StopFile = "stop"
+
+	// PidFile records the running loop.sh or agent process, so a ralph-tui
+	// started after this one died can find and adopt or kill it.
+	PidFile = "loop.pid"
+
+	// OutputFile is where loop.sh copies its output, so a ralph-tui that
+	// adopts it can follow what it writes once this one's pipes are gone.
+	OutputFile = "output.log"
+
+	// planWorkDefaultIterations matches loop.sh's default for plan-work mode.
+	planWorkDefaultIterations = 5
+)
+
+var (
+	// loopMarkerRegex matches the iteration boundary printed by loop.sh.
+	loopMarkerRegex = regexp.MustCompile(`=+ LOOP (\d+) =+`)
+
+	// pausedMarkerRegex and resumedMarkerRegex match loop.sh's pause handshake.
+	pausedMarkerRegex  = regexp.MustCompile(`^PAUSED after iteration \d+`)
+	resumedMarkerRegex = regexp.MustCompile(`^RESUMED at iteration \d+`)
+
+	// stoppedMarkerRegex matches loop.sh honouring the stop sentinel.
+	stoppedMarkerRegex = regexp.MustCompile(`^STOPPED after iteration \d+`)
+)
+
+// Config describes a single loop run.
+type Config struct {
+	Runner        state.Runner
+	Mode          state.Mode
+	MaxIterations int // 0 = unlimited (plan-work defaults to 5 like loop.sh)
+	WorkDesc      string
+
+	// Script runner
+	ScriptPath string
+	ControlDir string // Directory for sentinel files, defaults to .ralph
+
+	// Native runner
+	PromptDir    string   // Directory containing PROMPT_*.md
+	AgentCommand string   // Agent binary, defaults to opencode
+	AgentArgs    []string // Arguments; PromptPlaceholder is replaced by the prompt
+	Push         bool     // Push the current branch after each iteration
+
+	// Restart policy for a loop that crashes (default: never)
+	Restart state.RestartPolicy
+
+	// Per-iteration watchdog (default: disabled)
+	Timeout state.TimeoutPolicy
+
+	// Stall detection when the agent goes quiet (default: disabled)
+	Stall state.StallPolicy
+
+	// Environment, working directory and extra arguments of the script or
+	// agent. A working directory also anchors the prompt and control dirs.
+	Start process.StartOptions
+
+	// Signal ladders for stop, immediate stop and timeouts (default: the
+	// process package's defaults)
+	Escalation process.EscalationConfig
+}
+
+// DefaultAgentArgs returns the opencode invocation used by loop.sh.
+func DefaultAgentArgs() []string {
+	return []string{"run", PromptPlaceholder, "--model", DefaultModel, "--agent", "build"}
+}
+
+// withDefaults fills in unset fields.
+func (c Config) withDefaults() Config {
+	if c.Runner == "" {
+		c.Runner = state.RunnerNative
+	}
+	if c.Mode == "" {
+		c.Mode = state.ModeBuild
+	}
+	if c.ScriptPath == "" {
+		c.ScriptPath = "./loop.sh"
+	}
+	if c.ControlDir == "" {
+		c.ControlDir = filepath.Join(c.Start.Dir, DefaultControlDir)
+	}
+	if c.PromptDir == "" {
+		c.PromptDir = c.Start.Dir
+		if c.PromptDir == "" {
+			c.PromptDir = "."
+		}
+	}
+	if c.AgentCommand == "" {
+		c.AgentCommand = DefaultAgentCommand
+	}
+	if c.AgentArgs == nil {
+		c.AgentArgs = DefaultAgentArgs()
+	}
+	if c.Restart.Mode == "" {
+		c.Restart.Mode = state.RestartNever
+	}
+	if c.Restart.Backoff <= 0 {
+		c.Restart.Backoff = DefaultRestartBackoff
+	}
+	if c.Restart.MaxBackoff <= 0 {
+		c.Restart.MaxBackoff = DefaultMaxRestartBackoff
+	}
+	if c.Timeout.Action == "" {
+		c.Timeout.Action = state.TimeoutContinue
+	}
+	if c.Stall.Action == "" {
+		c.Stall.Action = state.StallWarn
+	}
+	return c
+}
+
+// maxIterations returns the effective iteration limit (0 = unlimited).
+func (c Config) maxIterations() int {
+	if c.MaxIterations == 0 && c.Mode == state.ModePlanWork {
+		return planWorkDefaultIterations
+	}
+	return c.MaxIterations
+}
+
+// agentArgs substitutes the prompt into the configured agent arguments.
+func (c Config) agentArgs(prompt string) []string {
+	args := make([]string, len(c.AgentArgs))
+	for i, arg := range c.AgentArgs {
+		if arg == PromptPlaceholder {
+			arg = prompt
+		}
+		args[i] = arg
+	}
+	return args
+}
+
+// pauseFile returns the path of the pause sentinel.
+func (c Config) pauseFile() string {
+	return filepath.Join(c.ControlDir, PauseFile)
+}
+
+// stopFile returns the path of the stop sentinel.
+func (c Config) stopFile() string {
+	return filepath.Join(c.ControlDir, StopFile)
+}
+
+// startOptions returns the options processes are started with. loop.sh
+// gets the sentinel paths, as it checks them relative to its own directory
+// otherwise, and where to copy its output.
+func (c Config) startOptions() process.StartOptions {
+	opts := c.Start
+	if c.Runner != state.RunnerScript {
+		return opts
+	}
+	opts.Env = append(slices.Clone(opts.Env),
+		"RALPH_PAUSE_FILE="+absPath(c.pauseFile()),
+		"RALPH_STOP_FILE="+absPath(c.stopFile()),
+		"RALPH_OUTPUT_FILE="+c.outputFile(),
+	)
+	return opts
+}
+
+// outputFile returns the absolute path loop.sh copies its output to; empty
+// for the native runner, whose agent writes no such copy.
+func (c Config) outputFile() string {
+	if c.Runner != state.RunnerScript {
+		return ""
+	}
+	return absPath(filepath.Join(c.ControlDir, OutputFile))
+}
+
+// absPath returns path made absolute, or path itself if that fails.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// PidFilePath returns where the loop records its running process.
+func (c Config) PidFilePath() string {
+	return filepath.Join(c.withDefaults().ControlDir, PidFile)
+}
+
+// scriptArgs builds loop.sh's positional arguments for the given limit.
+func (c Config) scriptArgs(maxIter int) []string {
+	args := []string{}
+	switch c.Mode {
+	case state.ModePlan:
+		args = append(args, "plan")
+	case state.ModePlanWork:
+		args = append(args, "plan-work", c.WorkDesc)
+	}
+	if maxIter > 0 {
+		args = append(args, strconv.Itoa(maxIter))
+	}
+	return args
+}
+
+// IterationResult records the outcome of one native iteration.
+type IterationResult struct {
+	Iteration  int
+	ExitCode   int              // -1 if terminated by a signal
+	Err        error            // Error returned by the agent process, if any
+	Exit       process.ExitInfo // Full outcome, including signal and stderr tail
+	Complete   bool             // Agent printed CompletionMarker
+	TimedOut   bool             // Killed by the watchdog
+	Stalled    bool             // Interrupted after going quiet
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Engine owns the Ralph loop lifecycle on top of a process.Manager.
+// The native runner invokes the agent once per iteration; the script runner
+// delegates to loop.sh and tracks iterations from its LOOP markers.
+// A crashed loop is restarted according to Config.Restart, resuming at the
+// first unfinished iteration. A watchdog kills iterations that exceed
+// Config.Timeout.
+//
+// Pausing never interrupts an iteration: the engine (or loop.sh, via the
+// pause sentinel) waits at the next iteration boundary until Resume.
+// StopAfterIteration likewise ends the loop at the next boundary (loop.sh
+// checks the stop sentinel).
+type Engine struct {
+	mgr            *process.Manager
+	cfg            Config
+	status         process.Status
+	completed      int // Iterations finished
+	current        int // Iteration in progress (0 when none)
+	offset         int // Script runner: iterations finished before this run
+	complete       bool
+	completeSeen   bool // CompletionMarker seen in the current run/iteration
+	stopRequested  bool
+	pauseRequested bool
+	stopAfter      bool // Stop at the next iteration boundary
+	syncOffset     bool // Adopted loop.sh: derive offset from its next marker
+	results        []IterationResult
+	restarts       []RestartEvent
+	backoffAttempt int       // Consecutive restarts without a finished iteration
+	iterationStart time.Time // Start of the running iteration (zero when none)
+	timedOut       bool      // The watchdog fired for the running iteration
+	stalled        bool      // The running iteration was interrupted for a stall
+	escalating     bool      // The watchdog is still signalling it
+	err            error
+	done           chan struct{} // Closed when the run goroutine exits
+	resumed        *sync.Cond    // Signalled on Resume and stop requests
+	onIteration    func(IterationResult)
+	mu             sync.RWMutex
+}
+
+// NewEngine creates a loop engine driving the given process manager.
+// The engine takes over the manager's output and stall callbacks.
+func NewEngine(mgr *process.Manager) *Engine {
+	done := make(chan struct{})
+	close(done)
+
+	e := &Engine{
+		mgr:    mgr,
+		status: process.StatusIdle,
+		done:   done,
+	}
+	e.resumed = sync.NewCond(&e.mu)
+	mgr.OnOutput(e.handleLine)
+	mgr.OnStall(e.handleStall)
+	return e
+}
+
+// Start begins a loop run in the background.
+func (e *Engine) Start(cfg Config) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Guard: Cannot start if already running, stopping or paused
+	if e.status == process.StatusRunning || e.status == process.StatusStopping {
+		return fmt.Errorf("loop already running or stopping")
+	}
+	if e.status == process.StatusPaused {
+		return fmt.Errorf("loop paused, resume it instead")
+	}
+
+	e.configureLocked(cfg)
+
+	// A sentinel left behind by a crashed session would pause or stop
+	// loop.sh at once
+	if err := os.Remove(e.cfg.pauseFile()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear pause sentinel: %w", err)
+	}
+	if err := os.Remove(e.cfg.stopFile()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear stop sentinel: %w", err)
+	}
+
+	e.resetLocked()
+	e.emit("loop started")
+
+	go e.run(e.done)
+
+	return nil
+}
+
+// Adopt takes over a loop process left running by a ralph-tui that died,
+// as recorded in its pidfile. The engine watches it until it exits; it can
+// be stopped, frozen, and for loop.sh paused or stopped after the current
+// iteration through the sentinels. The loop is not continued afterwards.
+func (e *Engine) Adopt(cfg Config, p process.PidFile) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Guard: Cannot adopt if already running, stopping or paused
+	if e.status == process.StatusRunning || e.status == process.StatusStopping || e.status == process.StatusPaused {
+		return fmt.Errorf("loop already running or stopping")
+	}
+
+	e.configureLocked(cfg)
+	if err := e.mgr.Adopt(p); err != nil {
+		return err
+	}
+
+	e.resetLocked()
+	e.current = max(p.Iteration, 1)
+	e.completed = e.current - 1
+	e.syncOffset = e.cfg.Runner == state.RunnerScript
+	e.emit(fmt.Sprintf("adopted pid %d at iteration %d", p.PID, e.current))
+	if e.cfg.Runner != state.RunnerScript {
+		e.mgr.AppendLog(fmt.Sprintf("Only the agent of iteration %d is watched; the next iteration will not be started", e.current))
+	}
+
+	go e.runAdopted(e.done)
+
+	return nil
+}
+
+// configureLocked applies a run's configuration to the engine and manager.
+// Must be called with e.mu held.
+func (e *Engine) configureLocked(cfg Config) {
+	e.cfg = cfg.withDefaults()
+	e.mgr.SetStallTimeout(e.cfg.Stall.Quiet)
+	e.mgr.SetStartOptions(e.cfg.startOptions())
+	e.mgr.SetEscalation(e.cfg.Escalation)
+	e.mgr.SetPidFile(e.cfg.PidFilePath(), process.PidFile{
+		Mode:   string(e.cfg.Mode),
+		Runner: string(e.cfg.Runner),
+		Dir:    e.cfg.Start.Dir,
+		Output: e.cfg.outputFile(),
+	})
+}
+
+// resetLocked clears the previous run and marks a new one running. Must be
+// called with e.mu held.
+func (e *Engine) resetLocked() {
+	e.completed = 0
+	e.results = nil
+	e.restarts = nil
+	e.backoffAttempt = 0
+	e.iterationStart = time.Time{}
+	e.timedOut = false
+	e.stalled = false
+	e.current = 0
+	e.complete = false
+	e.stopRequested = false
+	e.pauseRequested = false
+	e.stopAfter = false
+	e.syncOffset = false
+	e.err = nil
+	e.status = process.StatusRunning
+	e.done = make(chan struct{})
+}
+
+// runAdopted waits for an adopted process to exit and records the end of
+// the run.
+func (e *Engine) runAdopted(done chan struct{}) {
+	_ = e.mgr.WaitForExit()
+
+	e.mu.Lock()
+	e.current = 0
+	e.pauseRequested = false
+	e.stopAfter = false
+	e.status = process.StatusStopped
+	e.emit("adopted loop ended")
+	pauseFile, stopFile := e.cfg.pauseFile(), e.cfg.stopFile()
+	e.mu.Unlock()
+
+	if e.cfg.Runner == state.RunnerScript {
+		_ = os.Remove(pauseFile)
+		_ = os.Remove(stopFile)
+	}
+
+	close(done)
+}
+
+// run drives the configured runner, restarting it per the restart policy,
+// and records the final status.
+func (e *Engine) run(done chan struct{}) {
+	var stopWatchdog chan struct{}
+	if e.cfg.Timeout.Limit > 0 {
+		stopWatchdog = make(chan struct{})
+		go e.watchdog(e.cfg.Timeout, stopWatchdog)
+	}
+
+	var err error
+	for {
+		if e.cfg.Runner == state.RunnerScript {
+			err = e.runScript()
+		} else {
+			err = e.runNative()
+		}
+
+		// Operator stops, completion and the iteration limit end the loop
+		e.mu.Lock()
+		var delay time.Duration
+		restart := false
+		if !e.stopRequested && !e.complete && !e.finishedLocked() &&
+			!errors.Is(err, errRestartsExhausted) && !errors.Is(err, errIterationTimeout) {
+			if err != nil {
+				delay, restart = e.planRestart(err.Error(), true)
+			} else {
+				delay, restart = e.planRestart("runner exited before finishing", false)
+			}
+		}
+		e.mu.Unlock()
+
+		if !restart {
+			break
+		}
+		if !e.sleepBackoff(delay) {
+			err = nil
+			break
+		}
+	}
+
+	if stopWatchdog != nil {
+		close(stopWatchdog)
+	}
+
+	e.mu.Lock()
+	e.err = err
+	e.current = 0
+	e.stopClockLocked()
+	e.pauseRequested = false
+	e.stopAfter = false
+	e.status = process.StatusStopped
+	switch {
+	case err != nil:
+		e.emit(fmt.Sprintf("loop failed: %v", err))
+	case e.complete:
+		e.emit("loop complete")
+	default:
+		e.emit("loop stopped")
+	}
+	pauseFile, stopFile := e.cfg.pauseFile(), e.cfg.stopFile()
+	e.mu.Unlock()
+
+	if e.cfg.Runner == state.RunnerScript {
+		_ = os.Remove(pauseFile)
+		_ = os.Remove(stopFile)
+	}
+
+	close(done)
+}
+
+// stopAtBoundary ends the loop at an iteration boundary if a stop after the
+// iteration was requested. Returns true if the loop should stop.
+func (e *Engine) stopAtBoundary() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.stopAfter && !e.stopRequested {
+		e.stopRequested = true
+		e.status = process.StatusStopping
+		e.mgr.AppendLog(fmt.Sprintf("STOPPED after iteration %d", e.completed))
+		e.emit(fmt.Sprintf("stopped after iteration %d", e.completed))
+	}
+	return e.stopRequested
+}
+
+// waitWhilePaused blocks at an iteration boundary while a pause is
+// requested. Returns false if the loop should stop instead.
+func (e *Engine) waitWhilePaused() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.pauseRequested && !e.stopRequested {
+		e.status = process.StatusPaused
+		e.mgr.AppendLog(fmt.Sprintf("PAUSED after iteration %d", e.completed))
+		e.emit(fmt.Sprintf("paused after iteration %d", e.completed))
+
+		for e.pauseRequested && !e.stopRequested {
+			e.resumed.Wait()
+		}
+
+		if !e.stopRequested {
+			e.mgr.AppendLog(fmt.Sprintf("RESUMED at iteration %d", e.completed+1))
+			e.emit(fmt.Sprintf("resumed at iteration %d", e.completed+1))
+		}
+	}
+
+	return !e.stopRequested
+}
+
+// runNative invokes the agent once per iteration until completion, the
+// iteration limit, or a stop request.
+func (e *Engine) runNative() error {
+	maxIter := e.cfg.maxIterations()
+
+	for {
+		if e.stopAtBoundary() || !e.waitWhilePaused() {
+			return nil
+		}
+
+		// Re-read the prompt every iteration so edits take effect
+		prompt, err := LoadPrompt(e.cfg.PromptDir, e.cfg.Mode, e.cfg.WorkDesc)
+		if err != nil {
+			return err
+		}
+
+		e.mu.Lock()
+		if e.stopRequested {
+			e.mu.Unlock()
+			return nil
+		}
+		if maxIter > 0 && e.completed >= maxIter {
+			e.mu.Unlock()
+			e.mgr.AppendLog(fmt.Sprintf("Reached max iterations: %d", maxIter))
+			return nil
+		}
+
+		e.waitForEscalationLocked()
+
+		iteration := e.completed + 1
+		e.current = iteration
+		e.completeSeen = false
+		e.mgr.SetIteration(iteration)
+		e.emit(fmt.Sprintf("iteration %d started", iteration))
+
+		// Start under the lock so a concurrent Stop either sees a running
+		// process or prevents this iteration from starting
+		e.startClockLocked()
+		err = e.mgr.Start(e.cfg.AgentCommand, e.cfg.agentArgs(prompt)...)
+		if err != nil {
+			e.stopClockLocked()
+		} else if !e.mgr.UsesPTY() {
+			// Agents read piped stdin as extra prompt text and would block on
+			// it; under a PTY stdin stays open for answering prompts
+			_ = e.mgr.CloseInput()
+		}
+		e.mu.Unlock()
+
+		if err != nil {
+			return fmt.Errorf("iteration %d: %w", iteration, err)
+		}
+
+		exitErr := e.mgr.WaitForExit()
+		exit, _ := e.mgr.LastExit()
+
+		e.mu.Lock()
+		e.stopClockLocked()
+		result := IterationResult{
+			Iteration:  iteration,
+			ExitCode:   exit.Code,
+			Err:        exitErr,
+			Exit:       exit,
+			Complete:   e.completeSeen,
+			TimedOut:   e.timedOut,
+			Stalled:    e.stalled,
+			StartedAt:  exit.StartedAt,
+			FinishedAt: exit.FinishedAt,
+		}
+		e.results = append(e.results, result)
+		stopped := e.stopRequested
+		e.complete = result.Complete
+		e.emit(fmt.Sprintf("iteration %d finished (%s)", iteration, exit))
+
+		// An interrupted (timed-out or stalled) iteration counts as
+		// finished; the timeout policy decides whether the loop goes on
+		interrupted := result.TimedOut || result.Stalled
+		if result.TimedOut && !stopped && !result.Complete && e.cfg.Timeout.Action == state.TimeoutStop {
+			e.completed = iteration
+			e.mu.Unlock()
+			return fmt.Errorf("iteration %d timed out after %s: %w", iteration, e.cfg.Timeout.Limit, errIterationTimeout)
+		}
+
+		// Unless restarts are disabled, a crashed iteration is retried
+		// after a backoff instead of counting as finished
+		var delay time.Duration
+		retry := false
+		if !stopped && !e.stopAfter && !result.Complete && !interrupted && !exit.Success() && e.cfg.Restart.Mode != state.RestartNever {
+			delay, retry = e.planRestart(fmt.Sprintf("iteration %d %s", iteration, exit), true)
+			if !retry {
+				e.mu.Unlock()
+				return fmt.Errorf("iteration %d %s: %w", iteration, exit, errRestartsExhausted)
+			}
+		}
+
+		// An interrupted iteration does not count as finished
+		if !retry && (!stopped || result.Complete) {
+			e.completed = iteration
+			if exit.Success() {
+				e.backoffAttempt = 0
+			}
+		}
+		callback := e.onIteration
+		e.mu.Unlock()
+
+		if callback != nil {
+			callback(result)
+		}
+
+		if retry {
+			if !e.sleepBackoff(delay) {
+				return nil
+			}
+			continue
+		}
+
+		if result.Complete {
+			e.mgr.AppendLog(fmt.Sprintf("✓ All tasks complete! Completed at iteration %d", iteration))
+			return nil
+		}
+		if stopped {
+			return nil
+		}
+
+		if e.cfg.Push {
+			e.push()
+		}
+
+		e.mgr.AppendLog(fmt.Sprintf("======================== LOOP %d ========================", iteration))
+	}
+}
+
+// runScript runs loop.sh, passing the remaining iteration budget. When the
+// watchdog or stall handling interrupts an iteration it takes loop.sh down
+// with it; the script is then started again for the next iteration unless
+// the timeout policy stops the loop.
+func (e *Engine) runScript() error {
+	for {
+		// Interrupted iterations restart loop.sh; honour a pending stop
+		if e.stopAtBoundary() {
+			return nil
+		}
+
+		e.mu.Lock()
+		if e.stopRequested {
+			e.mu.Unlock()
+			return nil
+		}
+		e.waitForEscalationLocked()
+
+		maxIter := e.cfg.maxIterations()
+		remaining := 0
+		if maxIter > 0 {
+			remaining = maxIter - e.completed
+			if remaining <= 0 {
+				e.mu.Unlock()
+				return nil
+			}
+		}
+
+		e.offset = e.completed
+		e.current = e.completed + 1
+		e.completeSeen = false
+		e.mgr.SetIteration(e.current)
+		e.emit(fmt.Sprintf("iteration %d started", e.current))
+		e.startClockLocked()
+		err := e.mgr.Start(e.cfg.ScriptPath, e.cfg.scriptArgs(remaining)...)
+		if err != nil {
+			e.stopClockLocked()
+		}
+		e.mu.Unlock()
+
+		if err != nil {
+			return err
+		}
+
+		exitErr := e.mgr.WaitForExit()
+
+		e.mu.Lock()
+		e.stopClockLocked()
+		e.complete = e.completeSeen
+
+		if (e.timedOut || e.stalled) && !e.stopRequested && !e.complete {
+			iteration := e.current
+			e.completed = iteration
+			if e.timedOut && e.cfg.Timeout.Action == state.TimeoutStop {
+				e.mu.Unlock()
+				return fmt.Errorf("iteration %d timed out after %s: %w", iteration, e.cfg.Timeout.Limit, errIterationTimeout)
+			}
+			e.mu.Unlock()
+			continue
+		}
+
+		crashed := exitErr != nil && !e.stopRequested && !e.complete
+		e.mu.Unlock()
+
+		if crashed {
+			return fmt.Errorf("script exited: %w", exitErr)
+		}
+		return nil
+	}
+}
+
+// push pushes the current branch, creating the upstream if needed.
+func (e *Engine) push() {
+	cmd := exec.Command("git", "branch", "--show-current")
+	cmd.Dir = e.cfg.Start.Dir
+	output, err := cmd.Output()
+	if err != nil {
+		e.mgr.AppendLog(fmt.Sprintf("Failed to determine branch: %v", err))
+		return
+	}
+	branch := strings.TrimSpace(string(output))
+
+	if err := e.git("push", "origin", branch); err != nil {
+		e.mgr.AppendLog("Failed to push. Creating remote branch...")
+		if err := e.git("push", "-u", "origin", branch); err != nil {
+			e.mgr.AppendLog(fmt.Sprintf("Failed to push: %v", err))
+		}
+	}
+}
+
+// git runs a git command and copies its output into the log buffer.
+func (e *Engine) git(args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = e.cfg.Start.Dir
+	output, err := cmd.CombinedOutput()
+	for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+		if line != "" {
+			e.mgr.AppendLog(line)
+		}
+	}
+	return err
+}
+
+// handleLine inspects process output for completion and iteration markers.
+func (e *Engine) handleLine(line string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if strings.Contains(line, CompletionMarker) {
+		e.completeSeen = true
+	}
+
+	if e.cfg.Runner != state.RunnerScript {
+		return
+	}
+
+	if matches := loopMarkerRegex.FindStringSubmatch(line); matches != nil {
+		n, err := strconv.Atoi(matches[1])
+		if err == nil {
+			// An adopted loop.sh counts from its own start, not ours
+			if e.syncOffset {
+				e.offset = e.completed + 1 - n
+				e.syncOffset = false
+			}
+			e.completed = e.offset + n
+			e.current = e.completed + 1
+			e.backoffAttempt = 0
+			e.startClockLocked()
+			e.mgr.SetIteration(e.current)
+			e.emit(fmt.Sprintf("iteration %d started", e.current))
+		}
+	}
+
+	// loop.sh acknowledges the pause sentinel at its iteration boundary
+	if pausedMarkerRegex.MatchString(line) && e.status == process.StatusRunning {
+		e.status = process.StatusPaused
+		e.current = 0
+		e.stopClockLocked()
+		e.emit(fmt.Sprintf("paused after iteration %d", e.completed))
+	}
+	if resumedMarkerRegex.MatchString(line) && e.status == process.StatusPaused {
+		e.status = process.StatusRunning
+		e.current = e.completed + 1
+		e.startClockLocked()
+		e.emit(fmt.Sprintf("resumed at iteration %d", e.current))
+	}
+
+	// loop.sh exits at its iteration boundary for the stop sentinel
+	if stoppedMarkerRegex.MatchString(line) && e.stopAfter {
+		e.stopRequested = true
+		e.status = process.StatusStopping
+		e.current = 0
+		e.stopClockLocked()
+		e.emit(fmt.Sprintf("stopped after iteration %d", e.completed))
+	}
+}
+
+// Pause requests a pause at the next iteration boundary. The current
+// iteration runs to completion; Resume continues with the next one.
+func (e *Engine) Pause() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Guard: Cannot pause if not running
+	if e.status != process.StatusRunning {
+		return fmt.Errorf("loop not running")
+	}
+	if e.pauseRequested {
+		return nil
+	}
+
+	if e.cfg.Runner == state.RunnerScript {
+		if err := os.MkdirAll(e.cfg.ControlDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create control dir: %w", err)
+		}
+		if err := os.WriteFile(e.cfg.pauseFile(), nil, 0o644); err != nil {
+			return fmt.Errorf("failed to write pause sentinel: %w", err)
+		}
+	}
+
+	e.pauseRequested = true
+	e.emit("pause requested")
+	return nil
+}
+
+// StopAfterIteration requests a stop at the next iteration boundary. The
+// current iteration runs to completion (and is pushed) first. A paused loop
+// is stopped right away.
+func (e *Engine) StopAfterIteration() error {
+	e.mu.Lock()
+
+	// Guard: Nothing is between iterations while paused
+	if e.status == process.StatusPaused {
+		e.mu.Unlock()
+		return e.Stop()
+	}
+	defer e.mu.Unlock()
+
+	// Guard: Cannot stop if not running
+	if e.status != process.StatusRunning {
+		return fmt.Errorf("loop not running")
+	}
+	if e.stopAfter {
+		return nil
+	}
+
+	if e.cfg.Runner == state.RunnerScript {
+		if err := os.MkdirAll(e.cfg.ControlDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create control dir: %w", err)
+		}
+		if err := os.WriteFile(e.cfg.stopFile(), nil, 0o644); err != nil {
+			return fmt.Errorf("failed to write stop sentinel: %w", err)
+		}
+	}
+
+	e.stopAfter = true
+	e.emit(fmt.Sprintf("stop after iteration %d requested", e.iterationLocked()))
+	return nil
+}
+
+// CancelStop withdraws a pending StopAfterIteration.
+func (e *Engine) CancelStop() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Guard: Nothing to cancel
+	if !e.stopAfter || e.stopRequested {
+		return fmt.Errorf("no stop pending")
+	}
+
+	if e.cfg.Runner == state.RunnerScript {
+		if err := os.Remove(e.cfg.stopFile()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove stop sentinel: %w", err)
+		}
+	}
+
+	e.stopAfter = false
+	e.emit("stop cancelled")
+	return nil
+}
+
+// Resume continues a paused loop, or cancels a pause that is still pending.
+func (e *Engine) Resume() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Guard: Nothing to resume
+	if !e.pauseRequested {
+		return fmt.Errorf("loop not paused")
+	}
+
+	if e.cfg.Runner == state.RunnerScript {
+		if err := os.Remove(e.cfg.pauseFile()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove pause sentinel: %w", err)
+		}
+	}
+
+	e.pauseRequested = false
+	if e.status == process.StatusPaused {
+		e.status = process.StatusRunning
+		e.current = e.completed + 1
+	}
+	e.resumed.Broadcast()
+	e.emit("resume requested")
+
+	return nil
+}
+
+// emit publishes the engine's state as a loop event on the manager's
+// subscriptions. Must be called with e.mu held.
+func (e *Engine) emit(message string) {
+	e.mgr.Publish(process.Event{
+		Kind:      process.EventLoop,
+		Status:    e.status,
+		Iteration: e.iterationLocked(),
+		Message:   message,
+	})
+}
+
+// Stop gracefully stops the loop and waits for the run to end.
+func (e *Engine) Stop() error {
+	return e.halt(e.mgr.Stop)
+}
+
+// StopImmediate interrupts the loop (SIGINT by default) and waits for the
+// run to end.
+func (e *Engine) StopImmediate() error {
+	return e.halt(e.mgr.StopImmediate)
+}
+
+// halt requests the run to end, signals the current process and waits.
+func (e *Engine) halt(signal func() error) error {
+	e.mu.Lock()
+
+	// Guard: Cannot stop if not running or paused
+	if e.status != process.StatusRunning && e.status != process.StatusPaused {
+		e.mu.Unlock()
+		return fmt.Errorf("loop not running")
+	}
+
+	e.status = process.StatusStopping
+	e.stopRequested = true
+	e.resumed.Broadcast()
+	e.emit("stop requested")
+	done := e.done
+	e.mu.Unlock()
+
+	// A frozen process is signalled too; the ladder continues it
+	var err error
+	if e.mgr.IsRunning() || e.mgr.IsFrozen() {
+		// The process may exit on its own between the check and the signal
+		if err = signal(); errors.Is(err, process.ErrNotRunning) {
+			err = nil
+		}
+	}
+
+	<-done
+	return err
+}
+
+// Freeze suspends the running iteration in place (SIGSTOP).
+func (e *Engine) Freeze() error {
+	// Guard: Only a loop with a live process can be frozen
+	if status := e.Status(); status != process.StatusRunning && status != process.StatusPaused {
+		return fmt.Errorf("loop not running")
+	}
+	return e.mgr.Freeze()
+}
+
+// Thaw continues a frozen iteration (SIGCONT).
+func (e *Engine) Thaw() error {
+	return e.mgr.Thaw()
+}
+
+// Wait blocks until the current run ends and returns its error (if any).
+func (e *Engine) Wait() error {
+	e.mu.RLock()
+	done := e.done
+	e.mu.RUnlock()
+
+	<-done
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.err
+}
+
+// Status returns the loop status. Unlike the manager's status it stays
+// Running between native iterations; a frozen process reports Frozen.
+func (e *Engine) Status() process.Status {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if (e.status == process.StatusRunning || e.status == process.StatusPaused) && e.mgr.IsFrozen() {
+		return process.StatusFrozen
+	}
+	return e.status
+}
+
+// IsRunning returns true if the loop is running.
+func (e *Engine) IsRunning() bool {
+	return e.Status() == process.StatusRunning
+}
+
+// IsFrozen returns true if the loop's process group is frozen.
+func (e *Engine) IsFrozen() bool {
+	return e.Status() == process.StatusFrozen
+}
+
+// IsPaused returns true if the loop is paused.
+func (e *Engine) IsPaused() bool {
+	return e.Status() == process.StatusPaused
+}
+
+// StopPending returns true if a stop after the current iteration was
+// requested and the iteration has not finished yet.
+func (e *Engine) StopPending() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.stopAfter && !e.stopRequested
+}
+
+// PausePending returns true if a pause was requested but the current
+// iteration has not finished yet.
+func (e *Engine) PausePending() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.pauseRequested && e.status == process.StatusRunning
+}
+
+// Iteration returns the iteration in progress, or the last finished
+// iteration when none is running.
+func (e *Engine) Iteration() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.iterationLocked()
+}
+
+// iterationLocked implements Iteration. Must be called with e.mu held.
+func (e *Engine) iterationLocked() int {
+	if e.current > 0 {
+		return e.current
+	}
+	return e.completed
+}
+
+// Completed returns the number of finished iterations.
+func (e *Engine) Completed() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.completed
+}
+
+// IsComplete returns true if the agent signalled completion.
+func (e *Engine) IsComplete() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.complete
+}
+
+// Err returns the error that ended the last run, if any.
+func (e *Engine) Err() error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.err
+}
+
+// Results returns the outcome of every native iteration in this session.
+func (e *Engine) Results() []IterationResult {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	results := make([]IterationResult, len(e.results))
+	copy(results, e.results)
+	return results
+}
+
+// Manager returns the underlying process manager.
+func (e *Engine) Manager() *process.Manager {
+	return e.mgr
+}
+
+// Restarts returns the automatic restarts of the current or last run.
+func (e *Engine) Restarts() []RestartEvent {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	restarts := make([]RestartEvent, len(e.restarts))
+	copy(restarts, e.restarts)
+	return restarts
+}
+
+// OnIteration registers a callback invoked after each native iteration.
+func (e *Engine) OnIteration(fn func(IterationResult)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onIteration = fn
+}
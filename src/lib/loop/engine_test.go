@@ -0,0 +1,712 @@
+package loop
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alex/ralph-tui/src/lib/process"
+	"github.com/alex/ralph-tui/src/lib/state"
+)
+
+// logText joins the text of every captured log entry.
+func logText(eng *Engine) string {
+	var lines []string
+	for _, entry := range eng.Manager().GetLogs() {
+		lines = append(lines, entry.Text)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// newTestConfig returns a native config whose agent is a shell snippet.
+// The prompt is passed as $1 so scripts can echo or inspect it.
+func newTestConfig(t *testing.T, script string) Config {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, name := range []string{"PROMPT_build.md", "PROMPT_plan.md", "PROMPT_plan_work.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatalf("Failed to write prompt: %v", err)
+		}
+	}
+
+	return Config{
+		Runner:       state.RunnerNative,
+		Mode:         state.ModeBuild,
+		PromptDir:    dir,
+		ControlDir:   filepath.Join(dir, DefaultControlDir),
+		AgentCommand: "sh",
+		AgentArgs:    []string{"-c", script, "agent", PromptPlaceholder},
+	}
+}
+
+func TestEngine_NativeHonorsMaxIterations(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	cfg := newTestConfig(t, `echo "prompt: $1"`)
+	cfg.MaxIterations = 3
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	if err := eng.Wait(); err != nil {
+		t.Fatalf("Engine run failed: %v", err)
+	}
+
+	if eng.Completed() != 3 {
+		t.Errorf("Expected 3 completed iterations, got %d", eng.Completed())
+	}
+	if eng.Status() != process.StatusStopped {
+		t.Errorf("Expected StatusStopped, got %v", eng.Status())
+	}
+
+	results := eng.Results()
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	for i, result := range results {
+		if result.Iteration != i+1 || result.ExitCode != 0 {
+			t.Errorf("Unexpected result %d: %+v", i, result)
+		}
+	}
+
+	logs := logText(eng)
+	if !strings.Contains(logs, "prompt: PROMPT_build.md") {
+		t.Errorf("Expected agent to receive build prompt, got logs:\n%s", logs)
+	}
+	if !strings.Contains(logs, "LOOP 2") {
+		t.Errorf("Expected LOOP markers in logs, got:\n%s", logs)
+	}
+}
+
+func TestEngine_NativeStopsOnCompletion(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	cfg := newTestConfig(t, `echo '<promise>COMPLETE</promise>'`)
+	cfg.MaxIterations = 5
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	_ = eng.Wait()
+
+	if !eng.IsComplete() {
+		t.Error("Expected engine to detect completion")
+	}
+	if eng.Completed() != 1 {
+		t.Errorf("Expected loop to end after 1 iteration, got %d", eng.Completed())
+	}
+}
+
+func TestEngine_NativeRecordsExitCodes(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	cfg := newTestConfig(t, `exit 3`)
+	cfg.MaxIterations = 2
+
+	var callbacks int
+	eng.OnIteration(func(IterationResult) { callbacks++ })
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	_ = eng.Wait()
+
+	results := eng.Results()
+	if len(results) != 2 {
+		t.Fatalf("Expected failed iterations to continue the loop, got %d results", len(results))
+	}
+	if results[0].ExitCode != 3 {
+		t.Errorf("Expected exit code 3, got %d", results[0].ExitCode)
+	}
+	if callbacks != 2 {
+		t.Errorf("Expected 2 iteration callbacks, got %d", callbacks)
+	}
+}
+
+func TestEngine_NativeStartOptions(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	cfg := newTestConfig(t, `echo "prompt: $1 model: $MODEL extra: $2"; pwd`)
+	cfg.Start = process.StartOptions{
+		Env:       []string{"MODEL=test-model"},
+		Dir:       cfg.PromptDir,
+		ExtraArgs: []string{"--verbose"},
+	}
+	cfg.PromptDir = ""
+	cfg.MaxIterations = 1
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	if err := eng.Wait(); err != nil {
+		t.Fatalf("Engine run failed: %v", err)
+	}
+
+	// The prompt is read from the working directory
+	logs := logText(eng)
+	if !strings.Contains(logs, "prompt: PROMPT_build.md model: test-model extra: --verbose") {
+		t.Errorf("Expected start options applied to the agent, got logs:\n%s", logs)
+	}
+	if !strings.Contains(logs, cfg.Start.Dir) {
+		t.Errorf("Expected agent to run in %s, got logs:\n%s", cfg.Start.Dir, logs)
+	}
+}
+
+func TestEngine_NativeMissingPrompt(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	cfg := newTestConfig(t, `true`)
+	cfg.PromptDir = t.TempDir()
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	err := eng.Wait()
+	if err == nil || !strings.Contains(err.Error(), "failed to read prompt") {
+		t.Errorf("Expected prompt read error, got %v", err)
+	}
+}
+
+func TestEngine_StopPreventsNextIteration(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	cfg := newTestConfig(t, `sleep 30`)
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	// Give the first iteration time to start
+	time.Sleep(100 * time.Millisecond)
+
+	if err := eng.Stop(); err != nil {
+		t.Fatalf("Failed to stop engine: %v", err)
+	}
+
+	if eng.IsRunning() {
+		t.Error("Expected engine to be stopped")
+	}
+	if len(eng.Results()) != 1 {
+		t.Errorf("Expected exactly 1 iteration, got %d", len(eng.Results()))
+	}
+	if eng.Completed() != 0 {
+		t.Errorf("Expected interrupted iteration not to count, got %d", eng.Completed())
+	}
+}
+
+func TestEngine_PreventMultipleStarts(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	cfg := newTestConfig(t, `sleep 30`)
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	err := eng.Start(cfg)
+	if err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("Expected already running error, got %v", err)
+	}
+
+	_ = eng.StopImmediate()
+}
+
+func TestEngine_ScriptRunnerTracksMarkers(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "loop.sh")
+	body := `#!/bin/sh
+echo "args: $*"
+echo "======================== LOOP 1 ========================"
+echo "======================== LOOP 2 ========================"
+echo "<promise>COMPLETE</promise>"
+`
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+
+	cfg := Config{
+		Runner:        state.RunnerScript,
+		Mode:          state.ModePlan,
+		MaxIterations: 4,
+		ScriptPath:    script,
+		ControlDir:    dir,
+	}
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	if err := eng.Wait(); err != nil {
+		t.Fatalf("Script run failed: %v", err)
+	}
+
+	if eng.Completed() != 2 {
+		t.Errorf("Expected 2 completed iterations, got %d", eng.Completed())
+	}
+	if !eng.IsComplete() {
+		t.Error("Expected completion from script output")
+	}
+
+	logs := logText(eng)
+	if !strings.Contains(logs, "args: plan 4") {
+		t.Errorf("Expected script to receive 'plan 4', got logs:\n%s", logs)
+	}
+}
+
+func TestEngine_ScriptRunnerFailure(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "loop.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nexit 2\n"), 0o755); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+
+	cfg := Config{
+		Runner:     state.RunnerScript,
+		ScriptPath: script,
+		ControlDir: dir,
+	}
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	if err := eng.Wait(); err == nil {
+		t.Error("Expected error for crashed script")
+	}
+}
+
+// waitForStatus polls until the engine reaches the wanted status.
+func waitForStatus(t *testing.T, eng *Engine, want process.Status) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for eng.Status() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %v, status is %v", want, eng.Status())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEngine_NativePauseFinishesIteration(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	cfg := newTestConfig(t, `sleep 0.2; echo finished`)
+	cfg.MaxIterations = 3
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	// Pause while iteration 1 is still running
+	time.Sleep(50 * time.Millisecond)
+	if err := eng.Pause(); err != nil {
+		t.Fatalf("Failed to pause: %v", err)
+	}
+	if !eng.PausePending() {
+		t.Error("Expected pause to be pending during the iteration")
+	}
+
+	waitForStatus(t, eng, process.StatusPaused)
+
+	results := eng.Results()
+	if len(results) != 1 || results[0].ExitCode != 0 {
+		t.Fatalf("Expected iteration 1 to finish cleanly, got %+v", results)
+	}
+	if eng.Iteration() != 1 {
+		t.Errorf("Expected iteration 1 while paused, got %d", eng.Iteration())
+	}
+
+	if err := eng.Resume(); err != nil {
+		t.Fatalf("Failed to resume: %v", err)
+	}
+	if err := eng.Wait(); err != nil {
+		t.Fatalf("Engine run failed: %v", err)
+	}
+
+	if eng.Completed() != 3 {
+		t.Errorf("Expected resume to continue to iteration 3, got %d", eng.Completed())
+	}
+}
+
+func TestEngine_StopWhileFrozen(t *testing.T) {
+	for name, stop := range map[string]func(*Engine) error{
+		"Stop":          (*Engine).Stop,
+		"StopImmediate": (*Engine).StopImmediate,
+	} {
+		t.Run(name, func(t *testing.T) {
+			eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+			cfg := newTestConfig(t, `sleep 30`)
+
+			if err := eng.Start(cfg); err != nil {
+				t.Fatalf("Failed to start engine: %v", err)
+			}
+			time.Sleep(100 * time.Millisecond)
+			if err := eng.Freeze(); err != nil {
+				t.Fatalf("Failed to freeze: %v", err)
+			}
+			waitForStatus(t, eng, process.StatusFrozen)
+
+			result := make(chan error, 1)
+			go func() { result <- stop(eng) }()
+			select {
+			case err := <-result:
+				if err != nil {
+					t.Fatalf("Failed to stop frozen loop: %v", err)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatal("Stopping a frozen loop did not return")
+			}
+			if eng.Status() != process.StatusStopped {
+				t.Errorf("Expected StatusStopped, got %v", eng.Status())
+			}
+		})
+	}
+}
+
+func TestEngine_StopWhilePaused(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	cfg := newTestConfig(t, `true`)
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	if err := eng.Pause(); err != nil {
+		t.Fatalf("Failed to pause: %v", err)
+	}
+	waitForStatus(t, eng, process.StatusPaused)
+
+	if err := eng.Start(cfg); err == nil {
+		t.Error("Expected Start to fail while paused")
+	}
+
+	if err := eng.Stop(); err != nil {
+		t.Fatalf("Failed to stop paused engine: %v", err)
+	}
+	if eng.Status() != process.StatusStopped {
+		t.Errorf("Expected StatusStopped, got %v", eng.Status())
+	}
+}
+
+func TestEngine_ScriptPauseSentinel(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	dir := t.TempDir()
+	pauseFile := filepath.Join(dir, PauseFile)
+
+	// Same pause handshake as loop.sh, with shorter sleeps
+	script := filepath.Join(dir, "loop.sh")
+	body := `#!/bin/sh
+i=0
+while [ $i -lt 2 ]; do
+    if [ -f "$RALPH_PAUSE_FILE" ]; then
+        echo "PAUSED after iteration $i"
+        while [ -f "$RALPH_PAUSE_FILE" ]; do sleep 0.05; done
+        echo "RESUMED at iteration $((i + 1))"
+    fi
+    sleep 0.2
+    i=$((i + 1))
+    echo "======================== LOOP $i ========================"
+done
+`
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+
+	cfg := Config{
+		Runner:     state.RunnerScript,
+		ScriptPath: script,
+		ControlDir: dir,
+	}
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if err := eng.Pause(); err != nil {
+		t.Fatalf("Failed to pause: %v", err)
+	}
+	if _, err := os.Stat(pauseFile); err != nil {
+		t.Fatalf("Expected pause sentinel to exist: %v", err)
+	}
+
+	waitForStatus(t, eng, process.StatusPaused)
+	if eng.Completed() != 1 {
+		t.Errorf("Expected pause after iteration 1, got %d", eng.Completed())
+	}
+
+	if err := eng.Resume(); err != nil {
+		t.Fatalf("Failed to resume: %v", err)
+	}
+	if err := eng.Wait(); err != nil {
+		t.Fatalf("Script run failed: %v", err)
+	}
+
+	if eng.Completed() != 2 {
+		t.Errorf("Expected 2 completed iterations, got %d", eng.Completed())
+	}
+	if _, err := os.Stat(pauseFile); !os.IsNotExist(err) {
+		t.Error("Expected pause sentinel to be removed")
+	}
+}
+
+func TestEngine_NativeStopAfterIteration(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	cfg := newTestConfig(t, `sleep 0.2; echo finished`)
+	cfg.MaxIterations = 5
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	// Request the stop while iteration 1 is still running
+	time.Sleep(50 * time.Millisecond)
+	if err := eng.StopAfterIteration(); err != nil {
+		t.Fatalf("Failed to request stop: %v", err)
+	}
+	if !eng.StopPending() {
+		t.Error("Expected stop to be pending during the iteration")
+	}
+	if err := eng.Wait(); err != nil {
+		t.Fatalf("Engine run failed: %v", err)
+	}
+
+	results := eng.Results()
+	if len(results) != 1 || results[0].ExitCode != 0 {
+		t.Fatalf("Expected iteration 1 to finish cleanly, got %+v", results)
+	}
+	if eng.Completed() != 1 || eng.Status() != process.StatusStopped {
+		t.Errorf("Expected stop after iteration 1, got %d completed, status %v", eng.Completed(), eng.Status())
+	}
+	if logs := logText(eng); !strings.Contains(logs, "STOPPED after iteration 1") {
+		t.Errorf("Expected stop marker in logs, got:\n%s", logs)
+	}
+}
+
+func TestEngine_CancelStop(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	cfg := newTestConfig(t, `sleep 0.1`)
+	cfg.MaxIterations = 2
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	if err := eng.StopAfterIteration(); err != nil {
+		t.Fatalf("Failed to request stop: %v", err)
+	}
+	if err := eng.CancelStop(); err != nil {
+		t.Fatalf("Failed to cancel stop: %v", err)
+	}
+	if err := eng.Wait(); err != nil {
+		t.Fatalf("Engine run failed: %v", err)
+	}
+
+	if eng.Completed() != 2 {
+		t.Errorf("Expected the loop to run on to iteration 2, got %d", eng.Completed())
+	}
+}
+
+func TestEngine_ScriptStopSentinel(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	dir := t.TempDir()
+	stopFile := filepath.Join(dir, StopFile)
+
+	// Same stop check as loop.sh, with shorter sleeps
+	script := filepath.Join(dir, "loop.sh")
+	body := `#!/bin/sh
+i=0
+while [ $i -lt 5 ]; do
+    if [ -f "$RALPH_STOP_FILE" ]; then
+        rm -f "$RALPH_STOP_FILE"
+        echo "STOPPED after iteration $i"
+        exit 0
+    fi
+    sleep 0.2
+    i=$((i + 1))
+    echo "======================== LOOP $i ========================"
+done
+`
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+
+	cfg := Config{
+		Runner:     state.RunnerScript,
+		ScriptPath: script,
+		ControlDir: dir,
+		Restart:    state.RestartPolicy{Mode: state.RestartAlways},
+	}
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if err := eng.StopAfterIteration(); err != nil {
+		t.Fatalf("Failed to request stop: %v", err)
+	}
+	if _, err := os.Stat(stopFile); err != nil {
+		t.Fatalf("Expected stop sentinel to exist: %v", err)
+	}
+
+	if err := eng.Wait(); err != nil {
+		t.Fatalf("Script run failed: %v", err)
+	}
+
+	// A clean stop is not mistaken for an early exit to restart
+	if eng.Completed() != 1 {
+		t.Errorf("Expected stop after iteration 1, got %d", eng.Completed())
+	}
+	if restarts := eng.Restarts(); len(restarts) != 0 {
+		t.Errorf("Expected no restarts, got %+v", restarts)
+	}
+}
+
+func TestEngine_AdoptOrphan(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{
+		Runner:     state.RunnerScript,
+		Mode:       state.ModeBuild,
+		ControlDir: dir,
+	}
+
+	// Another manager's process stands in for a loop whose ralph-tui died
+	previous := process.NewManager(process.DefaultBufferSize)
+	previous.SetPidFile(cfg.PidFilePath(), process.PidFile{Mode: "build"})
+	previous.SetIteration(3)
+	if err := previous.Start("sleep", "30"); err != nil {
+		t.Fatalf("Failed to start orphan: %v", err)
+	}
+	defer previous.Stop()
+	orphan, err := process.ReadPidFile(cfg.PidFilePath())
+	if err != nil {
+		t.Fatalf("Failed to read pidfile: %v", err)
+	}
+
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+	if err := eng.Adopt(cfg, orphan); err != nil {
+		t.Fatalf("Failed to adopt: %v", err)
+	}
+	if !eng.IsRunning() || eng.Iteration() != 3 || eng.Completed() != 2 {
+		t.Errorf("Expected running at iteration 3, got %v at %d (%d completed)", eng.Status(), eng.Iteration(), eng.Completed())
+	}
+
+	// Guard: An adopted loop cannot be started over
+	if err := eng.Start(cfg); err == nil {
+		t.Error("Expected start refused while the adopted loop runs")
+	}
+
+	if err := eng.Stop(); err != nil {
+		t.Fatalf("Failed to stop adopted loop: %v", err)
+	}
+	if eng.Status() != process.StatusStopped {
+		t.Errorf("Expected StatusStopped, got %v", eng.Status())
+	}
+	if _, err := os.Stat(cfg.PidFilePath()); !os.IsNotExist(err) {
+		t.Errorf("Expected pidfile removed, got %v", err)
+	}
+}
+
+func TestEngine_AdoptFollowsScriptOutput(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{
+		Runner:     state.RunnerScript,
+		Mode:       state.ModeBuild,
+		ControlDir: dir,
+	}
+
+	// The orphaned loop.sh was restarted after two iterations and writes only
+	// to its output file, as its ralph-tui's pipes are gone
+	script := filepath.Join(dir, "loop.sh")
+	body := `#!/bin/sh
+exec >>"$RALPH_OUTPUT_FILE" 2>&1
+sleep 0.3
+echo "======================== LOOP 1 ========================"
+sleep 0.3
+echo "======================== LOOP 2 ========================"
+`
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+	previous := process.NewManager(process.DefaultBufferSize)
+	previous.SetStartOptions(cfg.startOptions())
+	previous.SetPidFile(cfg.PidFilePath(), process.PidFile{Output: cfg.outputFile()})
+	previous.SetIteration(3)
+	if err := previous.Start(script); err != nil {
+		t.Fatalf("Failed to start orphan: %v", err)
+	}
+	defer previous.Stop()
+	orphan, err := process.ReadPidFile(cfg.PidFilePath())
+	if err != nil {
+		t.Fatalf("Failed to read pidfile: %v", err)
+	}
+
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+	if err := eng.Adopt(cfg, orphan); err != nil {
+		t.Fatalf("Failed to adopt: %v", err)
+	}
+	if err := eng.Wait(); err != nil {
+		t.Fatalf("Adopted run failed: %v", err)
+	}
+
+	// The markers count from the orphan's start; iterations continue ours
+	if eng.Completed() != 4 {
+		t.Errorf("Expected 4 completed iterations, got %d", eng.Completed())
+	}
+	if logs := logText(eng); !strings.Contains(logs, "LOOP 2") {
+		t.Errorf("Expected followed output in logs, got:\n%s", logs)
+	}
+}
+
+func TestEngine_PublishesLoopEvents(t *testing.T) {
+	mgr := process.NewManager(process.DefaultBufferSize)
+	eng := NewEngine(mgr)
+	sub := mgr.Subscribe()
+	defer mgr.Unsubscribe(sub)
+
+	cfg := newTestConfig(t, `echo working`)
+	cfg.MaxIterations = 2
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	if err := eng.Wait(); err != nil {
+		t.Fatalf("Engine run failed: %v", err)
+	}
+
+	// Loop events arrive in order, ending with the stop
+	var messages []string
+	deadline := time.After(5 * time.Second)
+	for len(messages) == 0 || messages[len(messages)-1] != "loop stopped" {
+		select {
+		case ev := <-sub.Events():
+			if ev.Kind == process.EventLoop {
+				messages = append(messages, ev.Message)
+			}
+		case <-deadline:
+			t.Fatalf("Timed out waiting for loop events, got %v", messages)
+		}
+	}
+
+	expected := []string{
+		"loop started",
+		"iteration 1 started",
+		"iteration 1 finished (exited 0)",
+		"iteration 2 started",
+		"iteration 2 finished (exited 0)",
+		"loop stopped",
+	}
+	if strings.Join(messages, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected loop events %v, got %v", expected, messages)
+	}
+}
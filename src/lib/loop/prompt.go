@@ -0,0 +1,61 @@
+package loop
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/alex/ralph-tui/src/lib/state"
+)
+
+// envVarRegex matches $VAR and ${VAR} references the way envsubst does.
+// Unlike os.Expand it leaves shell specials such as $1 or $@ untouched.
+var envVarRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// PromptFile returns the prompt file name used for the given mode.
+func PromptFile(mode state.Mode) string {
+	switch mode {
+	case state.ModePlan:
+		return "PROMPT_plan.md"
+	case state.ModePlanWork:
+		return "PROMPT_plan_work.md"
+	default:
+		return "PROMPT_build.md"
+	}
+}
+
+// LoadPrompt reads the prompt for the given mode from dir. In plan-work mode
+// environment references are substituted with WORK_SCOPE set to workDesc,
+// mirroring the envsubst call in loop.sh.
+func LoadPrompt(dir string, mode state.Mode, workDesc string) (string, error) {
+	path := filepath.Join(dir, PromptFile(mode))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt: %w", err)
+	}
+
+	content := string(data)
+	if mode == state.ModePlanWork {
+		content = expandEnv(content, func(name string) string {
+			if name == "WORK_SCOPE" {
+				return workDesc
+			}
+			return os.Getenv(name)
+		})
+	}
+
+	return content, nil
+}
+
+// expandEnv replaces $VAR and ${VAR} references using lookup.
+func expandEnv(s string, lookup func(string) string) string {
+	return envVarRegex.ReplaceAllStringFunc(s, func(ref string) string {
+		matches := envVarRegex.FindStringSubmatch(ref)
+		name := matches[1]
+		if name == "" {
+			name = matches[2]
+		}
+		return lookup(name)
+	})
+}
@@ -0,0 +1,56 @@
+package loop
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alex/ralph-tui/src/lib/state"
+)
+
+func TestPromptFile(t *testing.T) {
+	cases := map[state.Mode]string{
+		state.ModeBuild:    "PROMPT_build.md",
+		state.ModePlan:     "PROMPT_plan.md",
+		state.ModePlanWork: "PROMPT_plan_work.md",
+	}
+	for mode, want := range cases {
+		if got := PromptFile(mode); got != want {
+			t.Errorf("PromptFile(%s) = %s, want %s", mode, got, want)
+		}
+	}
+}
+
+func TestLoadPrompt_PlanWorkSubstitution(t *testing.T) {
+	dir := t.TempDir()
+	content := "Scope: ${WORK_SCOPE}; again $WORK_SCOPE; cost $5; keep $@"
+	if err := os.WriteFile(filepath.Join(dir, "PROMPT_plan_work.md"), []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write prompt: %v", err)
+	}
+
+	got, err := LoadPrompt(dir, state.ModePlanWork, "user auth")
+	if err != nil {
+		t.Fatalf("LoadPrompt failed: %v", err)
+	}
+
+	want := "Scope: user auth; again user auth; cost $5; keep $@"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestLoadPrompt_BuildModeIsVerbatim(t *testing.T) {
+	dir := t.TempDir()
+	content := "Use ${WORK_SCOPE} literally"
+	if err := os.WriteFile(filepath.Join(dir, "PROMPT_build.md"), []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write prompt: %v", err)
+	}
+
+	got, err := LoadPrompt(dir, state.ModeBuild, "ignored")
+	if err != nil {
+		t.Fatalf("LoadPrompt failed: %v", err)
+	}
+	if got != content {
+		t.Errorf("expected verbatim prompt, got %q", got)
+	}
+}
@@ -0,0 +1,113 @@
+package loop
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alex/ralph-tui/src/lib/state"
+)
+
+const (
+	// DefaultRestartBackoff is the delay before the first automatic restart.
+	DefaultRestartBackoff = 2 * time.Second
+
+	// DefaultMaxRestartBackoff caps the exponential restart delay.
+	DefaultMaxRestartBackoff = 5 * time.Minute
+)
+
+// errRestartsExhausted ends a run whose restart budget is used up.
+var errRestartsExhausted = errors.New("restarts exhausted")
+
+// RestartEvent records one automatic restart of a crashed loop.
+type RestartEvent struct {
+	Attempt   int // 1-based count of restarts in this run
+	Time      time.Time
+	Iteration int           // Iteration the loop resumes at
+	Reason    string        // What ended the previous attempt
+	Delay     time.Duration // Backoff waited before restarting
+}
+
+// restartDelay returns the backoff before the given consecutive restart
+// (0-based): Backoff doubled per attempt, capped at MaxBackoff.
+func restartDelay(policy state.RestartPolicy, attempt int) time.Duration {
+	delay := policy.Backoff
+	for i := 0; i < attempt && delay < policy.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > policy.MaxBackoff {
+		delay = policy.MaxBackoff
+	}
+	return delay
+}
+
+// planRestart applies the restart policy to a loop that ended or failed
+// without being asked to. failed distinguishes crashes from early clean
+// exits. Returns the backoff to wait, or false if the loop should stay
+// down. Must be called with e.mu held.
+func (e *Engine) planRestart(reason string, failed bool) (time.Duration, bool) {
+	policy := e.cfg.Restart
+
+	switch policy.Mode {
+	case state.RestartOnFailure:
+		if !failed {
+			return 0, false
+		}
+	case state.RestartAlways:
+	default:
+		return 0, false
+	}
+
+	if policy.MaxRestarts > 0 && len(e.restarts) >= policy.MaxRestarts {
+		e.mgr.AppendLog(fmt.Sprintf("Giving up after %d restarts: %s", len(e.restarts), reason))
+		e.emit(fmt.Sprintf("giving up after %d restarts", len(e.restarts)))
+		return 0, false
+	}
+
+	delay := restartDelay(policy, e.backoffAttempt)
+	e.backoffAttempt++
+
+	event := RestartEvent{
+		Attempt:   len(e.restarts) + 1,
+		Time:      time.Now(),
+		Iteration: e.completed + 1,
+		Reason:    reason,
+		Delay:     delay,
+	}
+	e.restarts = append(e.restarts, event)
+
+	message := fmt.Sprintf("restart %d at iteration %d in %s: %s", event.Attempt, event.Iteration, delay, reason)
+	e.mgr.AppendLog("RESTART " + message)
+	e.emit(message)
+
+	return delay, true
+}
+
+// sleepBackoff waits out a restart delay. Returns false if the loop was
+// stopped in the meantime.
+func (e *Engine) sleepBackoff(delay time.Duration) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Wake the wait below when the delay is over; stop requests wake it early
+	deadline := time.Now().Add(delay)
+	timer := time.AfterFunc(delay, func() {
+		e.mu.Lock()
+		e.resumed.Broadcast()
+		e.mu.Unlock()
+	})
+	defer timer.Stop()
+
+	for !e.stopRequested && time.Now().Before(deadline) {
+		e.resumed.Wait()
+	}
+
+	return !e.stopRequested
+}
+
+// finishedLocked reports whether the loop reached its iteration limit.
+// Must be called with e.mu held.
+func (e *Engine) finishedLocked() bool {
+	maxIter := e.cfg.maxIterations()
+	return maxIter > 0 && e.completed >= maxIter
+}
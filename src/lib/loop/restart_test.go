@@ -0,0 +1,178 @@
+package loop
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alex/ralph-tui/src/lib/process"
+	"github.com/alex/ralph-tui/src/lib/state"
+)
+
+func TestRestartDelay_DoublesUpToCap(t *testing.T) {
+	policy := state.RestartPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for attempt, want := range expected {
+		if got := restartDelay(policy, attempt); got != want {
+			t.Errorf("Attempt %d: expected %v, got %v", attempt, want, got)
+		}
+	}
+}
+
+func TestEngine_NativeRetriesCrashedIteration(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	// Fail the first two invocations, then succeed
+	counter := filepath.Join(t.TempDir(), "count")
+	cfg := newTestConfig(t, `n=$(cat `+counter+` 2>/dev/null || echo 0); echo $((n+1)) > `+counter+`; [ "$n" -ge 2 ] || exit 1`)
+	cfg.MaxIterations = 2
+	cfg.Restart = state.RestartPolicy{Mode: state.RestartOnFailure, MaxRestarts: 5, Backoff: 10 * time.Millisecond}
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	if err := eng.Wait(); err != nil {
+		t.Fatalf("Engine run failed: %v", err)
+	}
+
+	if eng.Completed() != 2 {
+		t.Errorf("Expected 2 completed iterations, got %d", eng.Completed())
+	}
+	restarts := eng.Restarts()
+	if len(restarts) != 2 {
+		t.Fatalf("Expected 2 restarts, got %d", len(restarts))
+	}
+	// Both crashes happened in iteration 1, which is retried
+	for _, restart := range restarts {
+		if restart.Iteration != 1 || !strings.Contains(restart.Reason, "crashed with 1") {
+			t.Errorf("Unexpected restart: %+v", restart)
+		}
+	}
+	if restarts[1].Delay != 2*restarts[0].Delay {
+		t.Errorf("Expected doubled backoff, got %v then %v", restarts[0].Delay, restarts[1].Delay)
+	}
+	if len(eng.Results()) != 4 {
+		t.Errorf("Expected 4 attempts, got %d", len(eng.Results()))
+	}
+}
+
+func TestEngine_NativeGivesUpAfterMaxRestarts(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	cfg := newTestConfig(t, `exit 1`)
+	cfg.Restart = state.RestartPolicy{Mode: state.RestartOnFailure, MaxRestarts: 2, Backoff: 10 * time.Millisecond}
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	err := eng.Wait()
+	if err == nil || !strings.Contains(err.Error(), "restarts exhausted") {
+		t.Fatalf("Expected restarts exhausted error, got %v", err)
+	}
+
+	if len(eng.Restarts()) != 2 {
+		t.Errorf("Expected 2 restarts, got %d", len(eng.Restarts()))
+	}
+	if eng.Completed() != 0 {
+		t.Errorf("Expected no completed iterations, got %d", eng.Completed())
+	}
+	if logs := logText(eng); strings.Count(logs, "Giving up") != 1 {
+		t.Errorf("Expected a single give-up line, got logs:\n%s", logs)
+	}
+}
+
+func TestEngine_NeverPolicyContinuesAfterFailure(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	cfg := newTestConfig(t, `exit 1`)
+	cfg.MaxIterations = 2
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	if err := eng.Wait(); err != nil {
+		t.Fatalf("Engine run failed: %v", err)
+	}
+
+	if eng.Completed() != 2 || len(eng.Restarts()) != 0 {
+		t.Errorf("Expected 2 iterations without restarts, got %d and %d", eng.Completed(), len(eng.Restarts()))
+	}
+}
+
+func TestEngine_ScriptRestartResumesIterationCount(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	// The first run finishes one iteration and crashes; the second finishes the rest
+	dir := t.TempDir()
+	script := filepath.Join(dir, "loop.sh")
+	body := `#!/bin/sh
+echo "args: $*"
+echo "======================== LOOP 1 ========================"
+if [ ! -f ` + filepath.Join(dir, "crashed") + ` ]; then
+	touch ` + filepath.Join(dir, "crashed") + `
+	exit 1
+fi
+echo "======================== LOOP 2 ========================"
+`
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+
+	cfg := Config{
+		Runner:        state.RunnerScript,
+		MaxIterations: 3,
+		ScriptPath:    script,
+		ControlDir:    filepath.Join(dir, ".ralph"),
+		Restart:       state.RestartPolicy{Mode: state.RestartOnFailure, Backoff: 10 * time.Millisecond},
+	}
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	if err := eng.Wait(); err != nil {
+		t.Fatalf("Engine run failed: %v", err)
+	}
+
+	if eng.Completed() != 3 {
+		t.Errorf("Expected 3 completed iterations, got %d", eng.Completed())
+	}
+	restarts := eng.Restarts()
+	if len(restarts) != 1 || restarts[0].Iteration != 2 {
+		t.Fatalf("Expected one restart at iteration 2, got %+v", restarts)
+	}
+
+	logs := logText(eng)
+	if !strings.Contains(logs, "args: 3") || !strings.Contains(logs, "args: 2") {
+		t.Errorf("Expected restart with the remaining budget, got logs:\n%s", logs)
+	}
+}
+
+func TestEngine_StopDuringBackoff(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	cfg := newTestConfig(t, `exit 1`)
+	cfg.Restart = state.RestartPolicy{Mode: state.RestartOnFailure, Backoff: time.Minute}
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(eng.Restarts()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	start := time.Now()
+	if err := eng.Stop(); err != nil {
+		t.Fatalf("Failed to stop engine: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected stop to cut the backoff short, took %v", elapsed)
+	}
+	if err := eng.Err(); err != nil {
+		t.Errorf("Expected operator stop to end without error, got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package loop
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alex/ralph-tui/src/lib/process"
+	"github.com/alex/ralph-tui/src/lib/state"
+)
+
+// maxWatchdogInterval bounds how late the watchdog notices an expired limit.
+const maxWatchdogInterval = time.Second
+
+// errIterationTimeout ends a run whose timeout policy is to stop.
+var errIterationTimeout = errors.New("iteration timed out")
+
+// watchdogInterval returns how often to check a limit: a tenth of it, but
+// at most once a second.
+func watchdogInterval(limit time.Duration) time.Duration {
+	interval := limit / 10
+	if interval > maxWatchdogInterval {
+		interval = maxWatchdogInterval
+	}
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	return interval
+}
+
+// watchdog interrupts, terminates and finally kills an iteration that runs
+// longer than the configured limit. Runs until stop is closed.
+//
+// Stall handling shares the escalation: an interrupted iteration (timed out
+// or stalled) counts as finished and the runner moves on to the next one.
+func (e *Engine) watchdog(policy state.TimeoutPolicy, stop <-chan struct{}) {
+	ticker := time.NewTicker(watchdogInterval(policy.Limit))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		// A frozen iteration is on hold by request and is left alone
+		e.mu.Lock()
+		expired := !e.iterationStart.IsZero() && !e.timedOut && !e.stopRequested &&
+			time.Since(e.iterationStart) >= policy.Limit && !e.mgr.IsFrozen()
+		if expired {
+			e.timedOut = true
+			e.escalating = true
+			message := fmt.Sprintf("iteration %d timed out after %s", e.current, policy.Limit)
+			e.mgr.AppendLog("TIMEOUT " + message)
+			e.emit(message)
+		}
+		e.mu.Unlock()
+
+		if expired {
+			e.escalate(process.StopTimeout)
+		}
+	}
+}
+
+// handleStall applies the stall policy when the manager reports that the
+// agent has gone quiet.
+func (e *Engine) handleStall(quiet time.Duration) {
+	e.mu.Lock()
+
+	// Guard: Only act on a running iteration the loop still wants
+	if e.iterationStart.IsZero() || e.stopRequested || e.escalating {
+		e.mu.Unlock()
+		return
+	}
+
+	message := fmt.Sprintf("iteration %d stalled: no output for %s", e.current, quiet.Truncate(time.Second))
+	action := e.cfg.Stall.Action
+	switch action {
+	case state.StallInterrupt:
+		e.stalled = true
+		e.escalating = true
+		message += ", interrupting"
+	case state.StallStop:
+		message += ", stopping the loop"
+	}
+	e.mgr.AppendLog("STALL " + message)
+	e.emit(message)
+	e.mu.Unlock()
+
+	switch action {
+	case state.StallInterrupt:
+		e.escalate(process.StopStall)
+	case state.StallStop:
+		err := e.halt(func() error {
+			return e.mgr.Escalate(e.mgr.Escalation().Stop, process.StopStall)
+		})
+		if err != nil {
+			e.mgr.AppendLog(fmt.Sprintf("Failed to stop stalled loop: %v", err))
+		}
+	}
+}
+
+// escalate takes down the running iteration on the engine's behalf, then
+// lets the runner carry on. The caller sets e.escalating beforehand.
+func (e *Engine) escalate(cause process.StopCause) {
+	err := e.mgr.Escalate(e.mgr.Escalation().Timeout, cause)
+	if err != nil && !errors.Is(err, process.ErrNotRunning) {
+		e.mgr.AppendLog(fmt.Sprintf("Failed to stop iteration: %v", err))
+	}
+
+	e.mu.Lock()
+	e.escalating = false
+	e.resumed.Broadcast()
+	e.mu.Unlock()
+}
+
+// startClockLocked marks the start of an iteration for the watchdog.
+// Must be called with e.mu held.
+func (e *Engine) startClockLocked() {
+	e.iterationStart = time.Now()
+	e.timedOut = false
+	e.stalled = false
+}
+
+// stopClockLocked marks that no iteration is running. Must be called with
+// e.mu held.
+func (e *Engine) stopClockLocked() {
+	e.iterationStart = time.Time{}
+}
+
+// waitForEscalationLocked blocks while the watchdog is still taking down a
+// timed-out iteration, so it never signals the next one. Must be called with
+// e.mu held.
+func (e *Engine) waitForEscalationLocked() {
+	for e.escalating {
+		e.resumed.Wait()
+	}
+}
+
+// IterationElapsed returns how long the current iteration has been running;
+// false if none is.
+func (e *Engine) IterationElapsed() (time.Duration, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.iterationStart.IsZero() {
+		return 0, false
+	}
+	return time.Since(e.iterationStart), true
+}
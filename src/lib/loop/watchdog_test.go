@@ -0,0 +1,181 @@
+package loop
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alex/ralph-tui/src/lib/process"
+	"github.com/alex/ralph-tui/src/lib/state"
+)
+
+func TestEngine_WatchdogContinuesAfterTimeout(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	cfg := newTestConfig(t, `sleep 30`)
+	cfg.MaxIterations = 2
+	cfg.Timeout = state.TimeoutPolicy{Limit: 200 * time.Millisecond, Action: state.TimeoutContinue}
+
+	start := time.Now()
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	if err := eng.Wait(); err != nil {
+		t.Fatalf("Engine run failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Expected hung iterations to be cut short, took %v", elapsed)
+	}
+
+	if eng.Completed() != 2 {
+		t.Errorf("Expected 2 completed iterations, got %d", eng.Completed())
+	}
+	for _, result := range eng.Results() {
+		if !result.TimedOut {
+			t.Errorf("Expected iteration %d to time out: %+v", result.Iteration, result)
+		}
+	}
+
+	logs := logText(eng)
+	if !strings.Contains(logs, "iteration 1 timed out") || !strings.Contains(logs, "Sending SIGINT") {
+		t.Errorf("Expected timeout and escalation in logs, got:\n%s", logs)
+	}
+}
+
+func TestEngine_WatchdogStopsLoop(t *testing.T) {
+	mgr := process.NewManager(process.DefaultBufferSize)
+	eng := NewEngine(mgr)
+
+	cfg := newTestConfig(t, `sleep 30`)
+	cfg.MaxIterations = 3
+	cfg.Timeout = state.TimeoutPolicy{Limit: 200 * time.Millisecond, Action: state.TimeoutStop}
+	// Timeouts are governed by their own policy, not the restart policy
+	cfg.Restart = state.RestartPolicy{Mode: state.RestartAlways, Backoff: 10 * time.Millisecond}
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	err := eng.Wait()
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("Expected timeout error, got %v", err)
+	}
+
+	if eng.Completed() != 1 || len(eng.Restarts()) != 0 {
+		t.Errorf("Expected 1 iteration without restarts, got %d and %d", eng.Completed(), len(eng.Restarts()))
+	}
+	if exit, _ := mgr.LastExit(); exit.Cause != process.StopTimeout || exit.Requested {
+		t.Errorf("Expected exit caused by the timeout, got %+v", exit)
+	}
+}
+
+func TestEngine_WatchdogIgnoresFastIterations(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	cfg := newTestConfig(t, `echo quick`)
+	cfg.MaxIterations = 3
+	cfg.Timeout = state.TimeoutPolicy{Limit: 5 * time.Second}
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	if err := eng.Wait(); err != nil {
+		t.Fatalf("Engine run failed: %v", err)
+	}
+
+	for _, result := range eng.Results() {
+		if result.TimedOut {
+			t.Errorf("Unexpected timeout: %+v", result)
+		}
+	}
+	if _, running := eng.IterationElapsed(); running {
+		t.Error("Expected no iteration clock after the run")
+	}
+}
+
+func TestEngine_WatchdogRestartsScriptForNextIteration(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "loop.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho \"args: $*\"\nsleep 30\n"), 0o755); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+
+	cfg := Config{
+		Runner:        state.RunnerScript,
+		MaxIterations: 2,
+		ScriptPath:    script,
+		ControlDir:    filepath.Join(dir, ".ralph"),
+		Timeout:       state.TimeoutPolicy{Limit: 200 * time.Millisecond},
+	}
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	if err := eng.Wait(); err != nil {
+		t.Fatalf("Engine run failed: %v", err)
+	}
+
+	if eng.Completed() != 2 {
+		t.Errorf("Expected 2 completed iterations, got %d", eng.Completed())
+	}
+	logs := logText(eng)
+	if !strings.Contains(logs, "args: 2") || !strings.Contains(logs, "args: 1") {
+		t.Errorf("Expected loop.sh to be restarted with the remaining budget, got logs:\n%s", logs)
+	}
+}
+
+func TestEngine_StallInterruptMovesOn(t *testing.T) {
+	eng := NewEngine(process.NewManager(process.DefaultBufferSize))
+
+	cfg := newTestConfig(t, `echo thinking; sleep 30`)
+	cfg.MaxIterations = 2
+	cfg.Stall = state.StallPolicy{Quiet: 200 * time.Millisecond, Action: state.StallInterrupt}
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	if err := eng.Wait(); err != nil {
+		t.Fatalf("Engine run failed: %v", err)
+	}
+
+	if eng.Completed() != 2 {
+		t.Errorf("Expected 2 completed iterations, got %d", eng.Completed())
+	}
+	for _, result := range eng.Results() {
+		if !result.Stalled || result.TimedOut {
+			t.Errorf("Expected iteration %d to be interrupted for a stall: %+v", result.Iteration, result)
+		}
+	}
+	if logs := logText(eng); !strings.Contains(logs, "iteration 1 stalled") {
+		t.Errorf("Expected stall in logs, got:\n%s", logs)
+	}
+}
+
+func TestEngine_StallStopsLoop(t *testing.T) {
+	mgr := process.NewManager(process.DefaultBufferSize)
+	eng := NewEngine(mgr)
+
+	cfg := newTestConfig(t, `sleep 30`)
+	cfg.MaxIterations = 3
+	cfg.Stall = state.StallPolicy{Quiet: 200 * time.Millisecond, Action: state.StallStop}
+
+	if err := eng.Start(cfg); err != nil {
+		t.Fatalf("Failed to start engine: %v", err)
+	}
+	if err := eng.Wait(); err != nil {
+		t.Fatalf("Engine run failed: %v", err)
+	}
+
+	if eng.Completed() != 0 {
+		t.Errorf("Expected the stalled iteration not to count, got %d", eng.Completed())
+	}
+	if eng.Status() != process.StatusStopped {
+		t.Errorf("Expected StatusStopped, got %v", eng.Status())
+	}
+	if exit, _ := mgr.LastExit(); exit.Cause != process.StopStall || exit.Requested {
+		t.Errorf("Expected exit caused by the stall, got %+v", exit)
+	}
+}
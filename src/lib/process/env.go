@@ -0,0 +1,88 @@
+package process
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// secretNameRegex matches environment variable names whose values must not
+// be shown, such as OPENAI_API_KEY or GITHUB_TOKEN.
+var secretNameRegex = regexp.MustCompile(`(?i)(KEY|TOKEN|SECRET|PASSWORD|PASSWD|CREDENTIAL|AUTH|COOKIE|SESSION)`)
+
+// maskedValue replaces the value of a secret variable.
+const maskedValue = "********"
+
+// StartOptions adjusts how subsequent commands are started.
+type StartOptions struct {
+	Env       []string // NAME=value entries added to (or replacing) the inherited environment
+	Unset     []string // Names removed from the inherited environment
+	Dir       string   // Working directory; empty inherits the TUI's
+	ExtraArgs []string // Appended to the arguments of every command
+}
+
+// Environ applies the additions and removals to base, a list of NAME=value
+// entries such as os.Environ(). Later entries win; the result is sorted.
+func (o StartOptions) Environ(base []string) []string {
+	env := make(map[string]string, len(base)+len(o.Env))
+	for _, entry := range base {
+		if name, value, ok := strings.Cut(entry, "="); ok {
+			env[name] = value
+		}
+	}
+	for _, name := range o.Unset {
+		delete(env, name)
+	}
+	for _, entry := range o.Env {
+		if name, value, ok := strings.Cut(entry, "="); ok {
+			env[name] = value
+		}
+	}
+
+	result := make([]string, 0, len(env))
+	for name, value := range env {
+		result = append(result, name+"="+value)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// customizesEnv reports whether the options change the inherited environment.
+func (o StartOptions) customizesEnv() bool {
+	return len(o.Env) > 0 || len(o.Unset) > 0
+}
+
+// MaskEnv returns env with the values of secret-looking variables replaced.
+func MaskEnv(env []string) []string {
+	masked := make([]string, len(env))
+	for i, entry := range env {
+		name, value, ok := strings.Cut(entry, "=")
+		if ok && value != "" && secretNameRegex.MatchString(name) {
+			entry = name + "=" + maskedValue
+		}
+		masked[i] = entry
+	}
+	return masked
+}
+
+// SetStartOptions sets the environment, working directory and extra
+// arguments for subsequent starts.
+func (m *Manager) SetStartOptions(opts StartOptions) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.startOpts = opts
+}
+
+// StartOptions returns the options applied to subsequent starts.
+func (m *Manager) StartOptions() StartOptions {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.startOpts
+}
+
+// Environment returns the environment subsequent commands start with,
+// sorted by name. Use MaskEnv before displaying it.
+func (m *Manager) Environment() []string {
+	return m.StartOptions().Environ(os.Environ())
+}
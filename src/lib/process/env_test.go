@@ -0,0 +1,53 @@
+package process
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStartOptions_Environ(t *testing.T) {
+	base := []string{"HOME=/home/ralph", "MODEL=old", "OPENAI_API_KEY=sk-123", "PATH=/usr/bin"}
+	opts := StartOptions{
+		Env:   []string{"MODEL=new", "WORK_SCOPE=auth"},
+		Unset: []string{"OPENAI_API_KEY", "MISSING"},
+	}
+
+	got := opts.Environ(base)
+	want := []string{"HOME=/home/ralph", "MODEL=new", "PATH=/usr/bin", "WORK_SCOPE=auth"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestMaskEnv(t *testing.T) {
+	env := []string{"ANTHROPIC_API_KEY=sk-ant", "GITHUB_TOKEN=ghp", "MODEL=opus", "DB_PASSWORD=", "PATH=/bin"}
+
+	got := MaskEnv(env)
+	want := []string{"ANTHROPIC_API_KEY=********", "GITHUB_TOKEN=********", "MODEL=opus", "DB_PASSWORD=", "PATH=/bin"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestManager_StartOptions(t *testing.T) {
+	t.Setenv("RALPH_TEST_INHERITED", "yes")
+	dir := t.TempDir()
+
+	mgr := NewManager(DefaultBufferSize)
+	mgr.SetStartOptions(StartOptions{
+		Env:       []string{"RALPH_TEST_ADDED=added"},
+		Unset:     []string{"RALPH_TEST_INHERITED"},
+		Dir:       dir,
+		ExtraArgs: []string{"extra"},
+	})
+
+	script := `echo "added=$RALPH_TEST_ADDED inherited=${RALPH_TEST_INHERITED:-unset}"; pwd; echo "arg=$1"`
+	if err := mgr.Start("sh", "-c", script, "sh"); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	_ = mgr.WaitForExit()
+
+	waitForLog(t, mgr, "added=added inherited=unset")
+	waitForLog(t, mgr, dir)
+	waitForLog(t, mgr, "arg=extra")
+}
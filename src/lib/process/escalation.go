@@ -0,0 +1,215 @@
+package process
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// killWait is how long a ladder waits for the process to go after SIGKILL.
+const killWait = 5 * time.Second
+
+// SignalStep is one rung of an escalation ladder: send Signal to the process
+// group, then give it Wait to exit before moving on to the next step.
+type SignalStep struct {
+	Signal syscall.Signal
+	Wait   time.Duration
+}
+
+// EscalationPolicy is an ordered ladder of signal steps used to take down
+// the process group. It always ends in SIGKILL.
+type EscalationPolicy []SignalStep
+
+var (
+	// DefaultStopEscalation asks the process to terminate and gives it time
+	// to clean up before killing it.
+	DefaultStopEscalation = EscalationPolicy{
+		{Signal: syscall.SIGTERM, Wait: 5 * time.Second},
+		{Signal: syscall.SIGKILL, Wait: killWait},
+	}
+
+	// DefaultInterruptEscalation interrupts the process like Ctrl+C and kills
+	// it soon after.
+	DefaultInterruptEscalation = EscalationPolicy{
+		{Signal: syscall.SIGINT, Wait: 2 * time.Second},
+		{Signal: syscall.SIGKILL, Wait: killWait},
+	}
+
+	// DefaultTimeoutEscalation interrupts a hung process first, like Ctrl+C
+	// would, then terminates and finally kills it.
+	DefaultTimeoutEscalation = EscalationPolicy{
+		{Signal: syscall.SIGINT, Wait: 5 * time.Second},
+		{Signal: syscall.SIGTERM, Wait: 5 * time.Second},
+		{Signal: syscall.SIGKILL, Wait: killWait},
+	}
+)
+
+// ParseEscalation parses a ladder such as "INT:2s,TERM:10s,KILL". Signals
+// may be given with or without the SIG prefix or as numbers; a step without
+// a wait waits 5s. SIGKILL is appended if the ladder does not end with it.
+func ParseEscalation(value string) (EscalationPolicy, error) {
+	var policy EscalationPolicy
+	for _, field := range strings.Split(value, ",") {
+		name, wait, hasWait := strings.Cut(strings.TrimSpace(field), ":")
+
+		sig, err := parseSignal(name)
+		if err != nil {
+			return nil, err
+		}
+		step := SignalStep{Signal: sig, Wait: killWait}
+		if hasWait {
+			if step.Wait, err = time.ParseDuration(wait); err != nil || step.Wait <= 0 {
+				return nil, fmt.Errorf("invalid wait '%s' for %s: must be a positive duration", wait, SignalName(sig))
+			}
+		}
+		policy = append(policy, step)
+	}
+	return policy.WithKill(), nil
+}
+
+// parseSignal converts "TERM", "SIGTERM" or "15" into a signal.
+func parseSignal(name string) (syscall.Signal, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if name == "" {
+		return 0, fmt.Errorf("missing signal")
+	}
+	if n, err := strconv.Atoi(name); err == nil {
+		if sig := syscall.Signal(n); unix.SignalName(sig) != "" {
+			return sig, nil
+		}
+		return 0, fmt.Errorf("unknown signal %d", n)
+	}
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	if sig := unix.SignalNum(name); sig != 0 {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("unknown signal '%s'", name)
+}
+
+// WithKill returns the policy ending in SIGKILL, appending the step if
+// needed so escalation always takes the process down.
+func (p EscalationPolicy) WithKill() EscalationPolicy {
+	if len(p) > 0 && p[len(p)-1].Signal == syscall.SIGKILL {
+		return p
+	}
+	return append(p[:len(p):len(p)], SignalStep{Signal: syscall.SIGKILL, Wait: killWait})
+}
+
+// String renders the ladder in the form ParseEscalation reads, e.g.
+// "SIGTERM:5s,SIGKILL".
+func (p EscalationPolicy) String() string {
+	steps := make([]string, len(p))
+	for i, step := range p {
+		steps[i] = SignalName(step.Signal)
+		if i < len(p)-1 || step.Wait != killWait {
+			steps[i] += ":" + step.Wait.String()
+		}
+	}
+	return strings.Join(steps, ",")
+}
+
+// EscalationConfig holds the ladder used by each way of taking the process
+// down. Unset ladders use the defaults.
+type EscalationConfig struct {
+	Stop      EscalationPolicy // Graceful stop
+	Interrupt EscalationPolicy // Immediate stop
+	Timeout   EscalationPolicy // Iteration timeout or stall
+}
+
+// WithDefaults fills in unset ladders.
+func (c EscalationConfig) WithDefaults() EscalationConfig {
+	if len(c.Stop) == 0 {
+		c.Stop = DefaultStopEscalation
+	}
+	if len(c.Interrupt) == 0 {
+		c.Interrupt = DefaultInterruptEscalation
+	}
+	if len(c.Timeout) == 0 {
+		c.Timeout = DefaultTimeoutEscalation
+	}
+	return c
+}
+
+// SetEscalation sets the ladders used by Stop, StopImmediate and timeouts,
+// for subsequent stops.
+func (m *Manager) SetEscalation(cfg EscalationConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.escalation = cfg.WithDefaults()
+}
+
+// Escalation returns the ladders used to take the process down.
+func (m *Manager) Escalation() EscalationConfig {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.escalation.WithDefaults()
+}
+
+// Stop takes the process down with the stop ladder (SIGTERM, then SIGKILL
+// after 5s by default).
+func (m *Manager) Stop() error {
+	return m.Escalate(m.Escalation().Stop, StopOperator)
+}
+
+// StopImmediate takes the process down with the interrupt ladder (SIGINT,
+// then SIGKILL after 2s by default).
+func (m *Manager) StopImmediate() error {
+	return m.Escalate(m.Escalation().Interrupt, StopOperator)
+}
+
+// Escalate walks the ladder until the process exits, logging each step.
+// The cause is reported in the exit info; an operator stop overrides an
+// escalation already under way. Returns an error if the process outlives
+// the last step.
+func (m *Manager) Escalate(policy EscalationPolicy, cause StopCause) error {
+	m.mu.Lock()
+
+	// Guard: Cannot escalate if not running
+	if m.status != StatusRunning && m.status != StatusStopping && m.status != StatusFrozen {
+		m.mu.Unlock()
+		return ErrNotRunning
+	}
+
+	process := m.process()
+	if process == nil {
+		m.mu.Unlock()
+		return fmt.Errorf("no process to signal")
+	}
+
+	frozen := m.status == StatusFrozen
+	if m.stopCause == StopNone || cause == StopOperator {
+		m.stopCause = cause
+	}
+	m.setStatus(StatusStopping)
+	doneChan := m.doneChan
+	m.mu.Unlock()
+
+	started := time.Now()
+	for _, step := range policy {
+		m.AppendLog(fmt.Sprintf("Sending %s to process group %d, waiting %s…", SignalName(step.Signal), process.Pid, step.Wait))
+		if err := signalGroup(process, step.Signal); err != nil {
+			return fmt.Errorf("failed to send %s: %w", SignalName(step.Signal), err)
+		}
+
+		// A frozen group only acts on the signal once it is continued
+		if frozen {
+			_ = signalGroup(process, syscall.SIGCONT)
+			frozen = false
+		}
+
+		select {
+		case <-doneChan:
+			m.AppendLog(fmt.Sprintf("Process exited after %s (%s)", SignalName(step.Signal), time.Since(started).Round(time.Millisecond)))
+			return nil
+		case <-time.After(step.Wait):
+		}
+	}
+
+	return fmt.Errorf("process still running after %d signals", len(policy))
+}
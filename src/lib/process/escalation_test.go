@@ -0,0 +1,91 @@
+package process
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseEscalation(t *testing.T) {
+	policy, err := ParseEscalation("int:2s, SIGTERM:30s,9")
+	if err != nil {
+		t.Fatalf("Failed to parse ladder: %v", err)
+	}
+	want := EscalationPolicy{
+		{Signal: syscall.SIGINT, Wait: 2 * time.Second},
+		{Signal: syscall.SIGTERM, Wait: 30 * time.Second},
+		{Signal: syscall.SIGKILL, Wait: killWait},
+	}
+	if len(policy) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, policy)
+	}
+	for i := range want {
+		if policy[i] != want[i] {
+			t.Errorf("Step %d: expected %+v, got %+v", i, want[i], policy[i])
+		}
+	}
+
+	// The rendered ladder parses back to itself
+	if got := policy.String(); got != "SIGINT:2s,SIGTERM:30s,SIGKILL" {
+		t.Errorf("Unexpected rendering %q", got)
+	}
+	again, err := ParseEscalation(policy.String())
+	if err != nil || again.String() != policy.String() {
+		t.Errorf("Expected round trip of %s, got %s (%v)", policy, again, err)
+	}
+}
+
+func TestParseEscalation_AppendsKill(t *testing.T) {
+	policy, err := ParseEscalation("TERM:10s")
+	if err != nil {
+		t.Fatalf("Failed to parse ladder: %v", err)
+	}
+	if len(policy) != 2 || policy[1].Signal != syscall.SIGKILL {
+		t.Errorf("Expected SIGKILL appended, got %s", policy)
+	}
+}
+
+func TestParseEscalation_Invalid(t *testing.T) {
+	for _, value := range []string{"", "NOPE:1s", "TERM:soon", "TERM:-1s", "999"} {
+		if _, err := ParseEscalation(value); err == nil {
+			t.Errorf("Expected error for %q", value)
+		}
+	}
+}
+
+func TestManager_StopUsesConfiguredLadder(t *testing.T) {
+	mgr := NewManager(DefaultBufferSize)
+	mgr.SetEscalation(EscalationConfig{
+		Stop: EscalationPolicy{
+			{Signal: syscall.SIGHUP, Wait: 200 * time.Millisecond},
+			{Signal: syscall.SIGKILL, Wait: 2 * time.Second},
+		},
+	})
+
+	// Ignore the configured first step so the ladder has to move on
+	if err := mgr.Start("sh", "-c", "trap '' HUP; echo ready; while :; do sleep 0.05; done"); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	if err := mgr.Stop(); err != nil {
+		t.Fatalf("Failed to stop process: %v", err)
+	}
+
+	var logs []string
+	for _, entry := range mgr.GetLogs() {
+		logs = append(logs, entry.Text)
+	}
+	joined := strings.Join(logs, "\n")
+	for _, want := range []string{"Sending SIGHUP", "waiting 200ms", "Sending SIGKILL", "Process exited after SIGKILL"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected %q in logs, got:\n%s", want, joined)
+		}
+	}
+
+	// Unset ladders keep the defaults
+	if got := mgr.Escalation().Interrupt.String(); got != DefaultInterruptEscalation.String() {
+		t.Errorf("Expected default interrupt ladder, got %s", got)
+	}
+}
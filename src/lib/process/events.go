@@ -0,0 +1,122 @@
+package process
+
+import (
+	"sync"
+	"time"
+)
+
+// EventKind identifies what an Event reports.
+type EventKind int
+
+const (
+	EventLog          EventKind = iota // A log entry was captured or appended
+	EventStatus                        // The process status changed
+	EventExit                          // The process exited
+	EventLoop                          // A loop runner changed state (iteration, pause, ...)
+	EventStall                         // The process has been quiet for the stall timeout
+	EventStallCleared                  // A stalled process produced output again
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventLog:
+		return "log"
+	case EventStatus:
+		return "status"
+	case EventExit:
+		return "exit"
+	case EventLoop:
+		return "loop"
+	case EventStall:
+		return "stall"
+	case EventStallCleared:
+		return "stall-cleared"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is delivered to subscribers in the order it happened.
+type Event struct {
+	Kind      EventKind
+	Time      time.Time
+	Entry     LogEntry // EventLog
+	Status    Status   // EventStatus, EventLoop
+	Err       error    // EventExit: result of cmd.Wait()
+	Exit      ExitInfo // EventExit: decoded outcome
+	Iteration int      // EventLoop
+	Message   string   // EventLoop, EventStall: human-readable description
+}
+
+// Subscription receives manager events. Events queue without limit until
+// read, so a slow consumer never loses any; Close releases the queue.
+type Subscription struct {
+	ch        chan Event
+	mu        sync.Mutex
+	queue     []Event
+	notify    chan struct{} // Wakes the pump when the queue grows
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// newSubscription creates a subscription and starts its delivery goroutine.
+func newSubscription() *Subscription {
+	s := &Subscription{
+		ch:     make(chan Event),
+		notify: make(chan struct{}, 1),
+		closed: make(chan struct{}),
+	}
+	go s.pump()
+	return s
+}
+
+// Events returns the channel events are delivered on. It is closed after Close.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Close stops delivery and discards queued events.
+func (s *Subscription) Close() {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+	})
+}
+
+// publish queues an event without blocking the publisher.
+func (s *Subscription) publish(ev Event) {
+	s.mu.Lock()
+	s.queue = append(s.queue, ev)
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+		// A wake-up is already pending
+	}
+}
+
+// pump moves queued events onto the channel until closed.
+func (s *Subscription) pump() {
+	defer close(s.ch)
+
+	for {
+		s.mu.Lock()
+		batch := s.queue
+		s.queue = nil
+		s.mu.Unlock()
+
+		for _, ev := range batch {
+			select {
+			case s.ch <- ev:
+			case <-s.closed:
+				return
+			}
+		}
+
+		select {
+		case <-s.notify:
+		case <-s.closed:
+			return
+		}
+	}
+}
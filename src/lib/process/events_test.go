@@ -0,0 +1,197 @@
+package process
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// collectEvents reads events until an exit event arrives or the timeout hits.
+func collectEvents(t *testing.T, sub *Subscription, timeout time.Duration) []Event {
+	t.Helper()
+
+	var events []Event
+	deadline := time.After(timeout)
+	for {
+		select {
+		case ev, ok := <-sub.Events():
+			if !ok {
+				return events
+			}
+			events = append(events, ev)
+			if ev.Kind == EventExit {
+				return events
+			}
+		case <-deadline:
+			t.Fatalf("Timed out waiting for exit event after %d events", len(events))
+		}
+	}
+}
+
+func TestSubscription_LifecycleEvents(t *testing.T) {
+	mgr := NewManager(DefaultBufferSize)
+	sub := mgr.Subscribe()
+	defer mgr.Unsubscribe(sub)
+
+	if err := mgr.Start("sh", "-c", "echo hello"); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+
+	events := collectEvents(t, sub, 5*time.Second)
+
+	// Expect: running status, the log line, exit, then stopped status
+	var kinds []EventKind
+	for _, ev := range events {
+		kinds = append(kinds, ev.Kind)
+	}
+	if len(events) < 3 {
+		t.Fatalf("Expected at least 3 events, got %v", kinds)
+	}
+	if events[0].Kind != EventStatus || events[0].Status != StatusRunning {
+		t.Errorf("Expected first event to be running status, got %v %v", events[0].Kind, events[0].Status)
+	}
+	if events[1].Kind != EventLog || events[1].Entry.Text != "hello" {
+		t.Errorf("Expected log event 'hello', got %v %q", events[1].Kind, events[1].Entry.Text)
+	}
+	if last := events[len(events)-1]; last.Kind != EventExit || last.Err != nil {
+		t.Errorf("Expected clean exit event last, got %v (err %v)", last.Kind, last.Err)
+	}
+}
+
+func TestSubscription_NoLossUnderBurst(t *testing.T) {
+	// The ring buffer holds far fewer lines than are produced, but a
+	// subscriber still sees every one of them in order
+	const lines = 5000
+	mgr := NewManager(100)
+	sub := mgr.Subscribe()
+	defer mgr.Unsubscribe(sub)
+
+	if err := mgr.Start("seq", "1", fmt.Sprint(lines)); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+
+	// Read slowly at first so the queue has to absorb the burst
+	time.Sleep(200 * time.Millisecond)
+
+	var got []string
+	for _, ev := range collectEvents(t, sub, 10*time.Second) {
+		if ev.Kind == EventLog {
+			got = append(got, ev.Entry.Text)
+		}
+	}
+
+	if len(got) != lines {
+		t.Fatalf("Expected %d log events, got %d", lines, len(got))
+	}
+	for i, text := range got {
+		if text != fmt.Sprint(i+1) {
+			t.Fatalf("Expected line %d to be %q, got %q", i, fmt.Sprint(i+1), text)
+		}
+	}
+}
+
+func TestSubscription_LoopEvents(t *testing.T) {
+	mgr := NewManager(DefaultBufferSize)
+	sub := mgr.Subscribe()
+	defer mgr.Unsubscribe(sub)
+
+	mgr.Publish(Event{Kind: EventLoop, Iteration: 3, Message: "iteration 3 started"})
+
+	select {
+	case ev := <-sub.Events():
+		if ev.Kind != EventLoop || ev.Iteration != 3 {
+			t.Errorf("Expected loop event for iteration 3, got %v %d", ev.Kind, ev.Iteration)
+		}
+		if ev.Time.IsZero() {
+			t.Error("Expected event time to be set")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for loop event")
+	}
+}
+
+func TestSubscription_UnsubscribeClosesChannel(t *testing.T) {
+	mgr := NewManager(DefaultBufferSize)
+	sub := mgr.Subscribe()
+
+	mgr.AppendLog("queued but never read")
+	mgr.Unsubscribe(sub)
+
+	// Delivery stops; the channel closes even with events still queued
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-sub.Events():
+			if !ok {
+				// Publishing after unsubscribe must not panic or block
+				mgr.AppendLog("after unsubscribe")
+				return
+			}
+		case <-deadline:
+			t.Fatal("Timed out waiting for channel to close")
+		}
+	}
+}
+
+func TestManager_StallDetection(t *testing.T) {
+	mgr := NewManager(DefaultBufferSize)
+	mgr.SetStallTimeout(200 * time.Millisecond)
+
+	stalls := make(chan time.Duration, 10)
+	mgr.OnStall(func(quiet time.Duration) {
+		stalls <- quiet
+	})
+
+	sub := mgr.Subscribe()
+	defer mgr.Unsubscribe(sub)
+
+	if err := mgr.Start("sh", "-c", "echo a; sleep 0.6; echo b"); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+
+	var kinds []EventKind
+	for _, ev := range collectEvents(t, sub, 5*time.Second) {
+		if ev.Kind == EventStall || ev.Kind == EventStallCleared {
+			kinds = append(kinds, ev.Kind)
+		}
+	}
+
+	if len(kinds) != 2 || kinds[0] != EventStall || kinds[1] != EventStallCleared {
+		t.Errorf("Expected stall then stall-cleared, got %v", kinds)
+	}
+	if len(stalls) != 1 {
+		t.Errorf("Expected one stall callback, got %d", len(stalls))
+	} else if quiet := <-stalls; quiet < 200*time.Millisecond {
+		t.Errorf("Expected stall after the quiet period, got %v", quiet)
+	}
+	if _, stalled := mgr.Stalled(); stalled {
+		t.Error("Expected stall to be cleared after exit")
+	}
+}
+
+func TestManager_FrozenProcessDoesNotStall(t *testing.T) {
+	mgr := NewManager(DefaultBufferSize)
+	mgr.SetStallTimeout(100 * time.Millisecond)
+
+	if err := mgr.Start("sleep", "10"); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	defer func() { _ = mgr.StopImmediate() }()
+
+	if err := mgr.Freeze(); err != nil {
+		t.Fatalf("Failed to freeze: %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	if _, stalled := mgr.Stalled(); stalled {
+		t.Error("Expected frozen process not to be reported as stalled")
+	}
+
+	// Thawing restarts the quiet period
+	if err := mgr.Thaw(); err != nil {
+		t.Fatalf("Failed to thaw: %v", err)
+	}
+	if _, stalled := mgr.Stalled(); stalled {
+		t.Error("Expected no stall right after thaw")
+	}
+}
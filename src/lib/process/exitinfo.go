@@ -0,0 +1,121 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// DefaultExitTailLines is how many trailing stderr lines ExitInfo keeps.
+const DefaultExitTailLines = 5
+
+// StopCause says why a process was taken down.
+type StopCause string
+
+const (
+	StopNone     StopCause = ""         // Nobody stopped it; it exited on its own
+	StopOperator StopCause = "operator" // Stop or StopImmediate
+	StopTimeout  StopCause = "timeout"  // The iteration ran over its time limit
+	StopStall    StopCause = "stall"    // The iteration produced no output for too long
+)
+
+// ExitInfo describes how a process run ended.
+type ExitInfo struct {
+	Code       int            // Exit status; -1 when killed by a signal
+	Signal     syscall.Signal // Terminating signal; 0 on a normal exit
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Cause      StopCause
+	Requested  bool     // The operator stopped the process (Stop/StopImmediate)
+	Stderr     []string // Last stderr lines (combined output under a PTY)
+	Err        error    // Result of cmd.Wait()
+}
+
+// newExitInfo decodes the result of cmd.Wait() into an ExitInfo.
+func newExitInfo(err error, startedAt, finishedAt time.Time, cause StopCause) ExitInfo {
+	info := ExitInfo{
+		StartedAt:  startedAt,
+		FinishedAt: finishedAt,
+		Cause:      cause,
+		Requested:  cause == StopOperator,
+		Err:        err,
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		info.Code = 0
+	case errors.As(err, &exitErr):
+		info.Code = exitErr.ExitCode()
+		if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			info.Signal = ws.Signal()
+		}
+	default:
+		// Waiting itself failed; the exit status is unknown
+		info.Code = -1
+	}
+
+	return info
+}
+
+// Duration returns how long the process ran.
+func (i ExitInfo) Duration() time.Duration {
+	return i.FinishedAt.Sub(i.StartedAt)
+}
+
+// Signaled reports whether the process was terminated by a signal.
+func (i ExitInfo) Signaled() bool {
+	return i.Signal != 0
+}
+
+// Success reports whether the process exited cleanly with status 0.
+func (i ExitInfo) Success() bool {
+	return i.Code == 0 && !i.Signaled() && i.Err == nil
+}
+
+// String summarizes the outcome, e.g. "exited 0", "crashed with 1",
+// "killed by SIGKILL" or "timed out, stopped by SIGINT".
+func (i ExitInfo) String() string {
+	switch {
+	case i.Signaled() && i.Requested:
+		return fmt.Sprintf("stopped by %s", SignalName(i.Signal))
+	case i.Signaled() && i.Cause != StopNone:
+		return fmt.Sprintf("%s, stopped by %s", i.Cause.describe(), SignalName(i.Signal))
+	case i.Signaled():
+		return fmt.Sprintf("killed by %s", SignalName(i.Signal))
+	case i.Code == 0:
+		return "exited 0"
+	case i.Requested:
+		return fmt.Sprintf("stopped, exited %d", i.Code)
+	case i.Cause != StopNone:
+		return fmt.Sprintf("%s, exited %d", i.Cause.describe(), i.Code)
+	case i.Code < 0:
+		return fmt.Sprintf("failed: %v", i.Err)
+	default:
+		return fmt.Sprintf("crashed with %d", i.Code)
+	}
+}
+
+// describe phrases the cause for an exit summary.
+func (c StopCause) describe() string {
+	switch c {
+	case StopTimeout:
+		return "timed out"
+	case StopStall:
+		return "stalled"
+	default:
+		return "stopped"
+	}
+}
+
+// SignalName returns the conventional name of sig, e.g. "SIGTERM".
+func SignalName(sig syscall.Signal) string {
+	if name := unix.SignalName(sig); name != "" {
+		return name
+	}
+	return fmt.Sprintf("signal %d", int(sig))
+}
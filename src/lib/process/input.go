@@ -0,0 +1,74 @@
+package process
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// promptRegex matches output lines that wait for an answer, such as
+// "Continue? [y/N]", "Overwrite (yes/no):" or "Press Enter to continue".
+var promptRegex = regexp.MustCompile(`(?i)((\[[yn]/[yn]\]|\([yn]/[yn]\)|\[yes/no\]|\(yes/no\)|continue\?)\W*$|press (enter|return|any key))`)
+
+// LooksLikePrompt reports whether an output line is asking for input.
+func LooksLikePrompt(text string) bool {
+	return promptRegex.MatchString(strings.TrimSpace(text))
+}
+
+// AcceptsInput reports whether the running process has a stdin to write to.
+// It is false once input was closed, or for an adopted process.
+func (m *Manager) AcceptsInput() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status == StatusRunning && m.stdin != nil
+}
+
+// WriteInput forwards text to the running process's stdin. Each line is
+// recorded in the logs as stdin so the transcript shows what was answered.
+func (m *Manager) WriteInput(text string) error {
+	m.mu.RLock()
+	stdin := m.stdin
+	running := m.status == StatusRunning
+	m.mu.RUnlock()
+
+	// Guard: Only a running process can take input
+	if !running || stdin == nil {
+		return ErrNotRunning
+	}
+
+	// Write outside the lock: a child that does not read blocks the write
+	if _, err := stdin.Write([]byte(text)); err != nil {
+		return fmt.Errorf("failed to write to stdin: %w", err)
+	}
+
+	// A bare newline (e.g. enter in raw mode) is not worth a log line
+	if trimmed := strings.TrimSuffix(text, "\n"); trimmed != "" {
+		for _, line := range strings.Split(trimmed, "\n") {
+			m.record(StreamStdin, line)
+		}
+	}
+	return nil
+}
+
+// CloseInput signals end of input to the running process: the pipe is
+// closed, or ^D is sent through a PTY.
+func (m *Manager) CloseInput() error {
+	m.mu.Lock()
+	stdin := m.stdin
+	usesPTY := m.pty != nil
+	if !usesPTY {
+		m.stdin = nil
+	}
+	m.mu.Unlock()
+
+	// Guard: Nothing to close
+	if stdin == nil {
+		return ErrNotRunning
+	}
+
+	if usesPTY {
+		_, err := stdin.Write([]byte{0x04})
+		return err
+	}
+	return stdin.Close()
+}
@@ -0,0 +1,125 @@
+package process
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestLooksLikePrompt(t *testing.T) {
+	prompts := []string{
+		"Continue? [y/N]",
+		"Continue? [y/N] ",
+		"Overwrite file (yes/no):",
+		"Press Enter to continue",
+		"Are you sure (Y/n)?",
+	}
+	for _, text := range prompts {
+		if !LooksLikePrompt(text) {
+			t.Errorf("Expected %q to look like a prompt", text)
+		}
+	}
+
+	lines := []string{
+		"Running tests...",
+		"Warning: IMPLEMENTATION_PLAN.md has uncommitted changes that will be overwritten",
+		"",
+	}
+	for _, text := range lines {
+		if LooksLikePrompt(text) {
+			t.Errorf("Expected %q not to look like a prompt", text)
+		}
+	}
+}
+
+// waitForLog polls the manager's logs for an entry with the given text.
+func waitForLog(t *testing.T, mgr *Manager, text string) LogEntry {
+	t.Helper()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, entry := range mgr.GetLogs() {
+			if entry.Text == text {
+				return entry
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for log line %q", text)
+	return LogEntry{}
+}
+
+func TestManager_WriteInput(t *testing.T) {
+	mgr := NewManager(DefaultBufferSize)
+
+	if err := mgr.WriteInput("y\n"); err == nil {
+		t.Error("Expected error writing to a stopped process")
+	}
+
+	script := `echo "Continue? [y/N]"; read -r answer; echo "answer: $answer"`
+	if err := mgr.Start("bash", "-c", script); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+
+	waitForLog(t, mgr, "Continue? [y/N]")
+	if err := mgr.WriteInput("yes\n"); err != nil {
+		t.Fatalf("Failed to write input: %v", err)
+	}
+	waitForLog(t, mgr, "answer: yes")
+	_ = mgr.WaitForExit()
+
+	if entry := waitForLog(t, mgr, "yes"); entry.Stream != StreamStdin {
+		t.Errorf("Expected input recorded as stdin, got %v", entry.Stream)
+	}
+}
+
+func TestManager_CloseInput(t *testing.T) {
+	mgr := NewManager(DefaultBufferSize)
+
+	if err := mgr.Start("sh", "-c", "cat; echo eof"); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	if !mgr.AcceptsInput() {
+		t.Error("Expected a running process to accept input")
+	}
+
+	if err := mgr.WriteInput("hello\n"); err != nil {
+		t.Fatalf("Failed to write input: %v", err)
+	}
+	if err := mgr.CloseInput(); err != nil {
+		t.Fatalf("Failed to close input: %v", err)
+	}
+	if mgr.AcceptsInput() {
+		t.Error("Expected no input accepted after close")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- mgr.WaitForExit() }()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		_ = mgr.StopImmediate()
+		t.Fatal("Expected cat to exit after EOF")
+	}
+	waitForLog(t, mgr, "eof")
+}
+
+func TestManager_PTYInput(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("PTY mode requires Linux")
+	}
+
+	mgr := NewManager(DefaultBufferSize)
+	mgr.SetPTY(true)
+
+	if err := mgr.Start("bash", "-c", `read -n 1 -r reply; echo; echo "reply: $reply"`); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if err := mgr.WriteInput("y"); err != nil {
+		t.Fatalf("Failed to write input: %v", err)
+	}
+	waitForLog(t, mgr, "reply: y")
+	_ = mgr.WaitForExit()
+}
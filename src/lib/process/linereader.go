@@ -0,0 +1,157 @@
+package process
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultMaxLineLength is the longest line captured as a single entry.
+	DefaultMaxLineLength = 64 * 1024
+
+	// ContinuationMarker ends every piece of a split line except the last.
+	ContinuationMarker = " ↩"
+
+	// maxReadErrors is how many consecutive read errors end a stream.
+	maxReadErrors = 3
+)
+
+// LongLinePolicy selects how lines longer than the limit are captured.
+type LongLinePolicy int
+
+const (
+	LongLineTruncate LongLinePolicy = iota // Keep the head and note how many bytes were elided
+	LongLineSplit                          // Split into entries ending in ContinuationMarker
+)
+
+// lineReader splits process output into lines of bounded length. Unlike
+// bufio.Scanner it never gives up on a long line.
+type lineReader struct {
+	r      *bufio.Reader
+	max    int
+	policy LongLinePolicy
+	carry  []byte // Start of a rune cut off at the end of the previous piece
+	read   int64  // Bytes of the stream read, including the carry
+}
+
+// newLineReader creates a reader that captures at most max bytes per entry.
+func newLineReader(r io.Reader, max int, policy LongLinePolicy) *lineReader {
+	if max <= 0 {
+		max = DefaultMaxLineLength
+	}
+	// bufio needs a little room beyond max for the newline
+	return &lineReader{r: bufio.NewReaderSize(r, max+1), max: max, policy: policy}
+}
+
+// next returns the next entry's text. At the end of the stream it returns
+// io.EOF; other errors are returned with any text read before them, and
+// reading may be retried.
+func (lr *lineReader) next() (string, error) {
+	chunk, err := lr.r.ReadSlice('\n')
+	lr.read += int64(len(chunk))
+
+	if errors.Is(err, bufio.ErrBufferFull) {
+		if lr.policy == LongLineSplit {
+			return lr.piece(chunk), nil
+		}
+		return lr.truncate(chunk)
+	}
+
+	line := lr.complete(chunk)
+	if err != nil && line == "" {
+		return "", err
+	}
+	if errors.Is(err, io.EOF) {
+		// Final line without a newline; EOF comes with the next call
+		err = nil
+	}
+	return line, err
+}
+
+// consumed returns the bytes of the stream the entries returned so far
+// cover.
+func (lr *lineReader) consumed() int64 {
+	return lr.read - int64(len(lr.carry))
+}
+
+// piece returns up to max bytes of a long line as one entry of a split
+// line. The rest, including a rune cut in half, is carried to the next piece
+// so a line ending right after a piece does not leave an empty entry.
+func (lr *lineReader) piece(chunk []byte) string {
+	data := append(lr.carry, chunk...)
+	cut := runeBoundary(data[:min(len(data), lr.max)])
+	lr.carry = append([]byte(nil), data[cut:]...)
+	return string(data[:cut]) + ContinuationMarker
+}
+
+// truncate keeps the head of a long line and discards the rest of it.
+func (lr *lineReader) truncate(chunk []byte) (string, error) {
+	head := append(lr.carry, chunk...)
+	lr.carry = nil
+	cut := runeBoundary(head[:min(len(head), lr.max)])
+	elided := len(head) - cut
+	head = head[:cut:cut]
+
+	// Skip to the end of the line
+	for {
+		rest, err := lr.r.ReadSlice('\n')
+		lr.read += int64(len(rest))
+		elided += len(bytes.TrimRight(rest, "\r\n"))
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		text := fmt.Sprintf("%s … %d bytes elided", head, elided)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return text, err
+		}
+		return text, nil
+	}
+}
+
+// complete finishes a line: carried bytes go in front, the line ending is
+// stripped (PTYs translate \n to \r\n).
+func (lr *lineReader) complete(chunk []byte) string {
+	line := string(lr.carry) + string(chunk)
+	lr.carry = nil
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r")
+}
+
+// runeBoundary returns the length of data without a trailing incomplete
+// UTF-8 sequence.
+func runeBoundary(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(data)
+}
+
+// isEndOfStream reports whether a read error means the writer side is gone.
+// A PTY master returns EIO once the last process holding the slave exits.
+func isEndOfStream(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, os.ErrClosed) || errors.Is(err, syscall.EIO)
+}
+
+// SetLineLimit sets the longest line captured as a single entry and what
+// happens to longer ones, for subsequent starts.
+func (m *Manager) SetLineLimit(max int, policy LongLinePolicy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if max <= 0 {
+		max = DefaultMaxLineLength
+	}
+	m.maxLine = max
+	m.longLines = policy
+}
@@ -0,0 +1,137 @@
+package process
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// readAllLines drains a lineReader, failing on unexpected errors.
+func readAllLines(t *testing.T, lr *lineReader) []string {
+	t.Helper()
+
+	var lines []string
+	for {
+		line, err := lr.next()
+		if errors.Is(err, io.EOF) {
+			return lines
+		}
+		if err != nil {
+			t.Fatalf("Unexpected read error: %v", err)
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestLineReader_ShortLines(t *testing.T) {
+	lr := newLineReader(strings.NewReader("one\r\ntwo\n\nthree"), 16, LongLineTruncate)
+
+	got := readAllLines(t, lr)
+	want := []string{"one", "two", "", "three"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestLineReader_Truncate(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	lr := newLineReader(strings.NewReader(long+"\nafter\n"), 16, LongLineTruncate)
+
+	got := readAllLines(t, lr)
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", got)
+	}
+	if want := strings.Repeat("x", 16) + " … 84 bytes elided"; got[0] != want {
+		t.Errorf("Expected %q, got %q", want, got[0])
+	}
+	if got[1] != "after" {
+		t.Errorf("Expected streaming to continue, got %q", got[1])
+	}
+}
+
+func TestLineReader_Split(t *testing.T) {
+	long := strings.Repeat("abcd", 10) // 40 bytes
+	lr := newLineReader(strings.NewReader(long+"\nafter\n"), 16, LongLineSplit)
+
+	got := readAllLines(t, lr)
+	want := []string{
+		"abcdabcdabcdabcd" + ContinuationMarker,
+		"abcdabcdabcdabcd" + ContinuationMarker,
+		"abcdabcd",
+		"after",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	// Joining the pieces restores the line
+	joined := strings.TrimSuffix(got[0], ContinuationMarker) + strings.TrimSuffix(got[1], ContinuationMarker) + got[2]
+	if joined != long {
+		t.Errorf("Expected pieces to join to the original line, got %q", joined)
+	}
+}
+
+func TestLineReader_SplitKeepsRunesWhole(t *testing.T) {
+	long := strings.Repeat("é", 20) // 2 bytes each
+	lr := newLineReader(strings.NewReader("a"+long+"\n"), 16, LongLineSplit)
+
+	var joined string
+	for _, line := range readAllLines(t, lr) {
+		piece := strings.TrimSuffix(line, ContinuationMarker)
+		if !strings.HasPrefix(piece, "é") && !strings.HasPrefix(piece, "a") {
+			t.Errorf("Piece starts mid-rune: %q", piece)
+		}
+		joined += piece
+	}
+	if joined != "a"+long {
+		t.Errorf("Expected pieces to join to the original line, got %q", joined)
+	}
+}
+
+// failingReader returns data, then an error, then more data.
+type failingReader struct {
+	chunks []string
+	errs   []error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+	chunk, err := r.chunks[0], r.errs[0]
+	r.chunks, r.errs = r.chunks[1:], r.errs[1:]
+	return copy(p, chunk), err
+}
+
+func TestLineReader_ErrorThenContinue(t *testing.T) {
+	boom := errors.New("boom")
+	lr := newLineReader(&failingReader{
+		chunks: []string{"first\n", "", "second\n"},
+		errs:   []error{nil, boom, nil},
+	}, 16, LongLineTruncate)
+
+	if line, err := lr.next(); line != "first" || err != nil {
+		t.Fatalf("Expected first line, got %q, %v", line, err)
+	}
+	if _, err := lr.next(); !errors.Is(err, boom) {
+		t.Fatalf("Expected read error, got %v", err)
+	}
+	if line, err := lr.next(); line != "second" || err != nil {
+		t.Fatalf("Expected reading to continue after the error, got %q, %v", line, err)
+	}
+}
+
+func TestManager_LongLineKeepsStreaming(t *testing.T) {
+	mgr := NewManager(DefaultBufferSize)
+	mgr.SetLineLimit(1024, LongLineTruncate)
+
+	// Well past bufio.Scanner's 64KB limit
+	if err := mgr.Start("sh", "-c", "head -c 200000 /dev/zero | tr '\\0' x; echo; echo after"); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	_ = mgr.WaitForExit()
+
+	waitForLog(t, mgr, "after")
+	waitForLog(t, mgr, strings.Repeat("x", 1024)+" … 198976 bytes elided")
+}
@@ -0,0 +1,52 @@
+package process
+
+import (
+	"sort"
+	"time"
+)
+
+// Stream identifies the source of a log entry.
+type Stream int
+
+const (
+	StreamStdout Stream = iota
+	StreamStderr
+	StreamSystem // Annotations written via AppendLog, not by the process
+	StreamStdin  // Operator input forwarded to the process
+)
+
+func (s Stream) String() string {
+	switch s {
+	case StreamStdout:
+		return "stdout"
+	case StreamStderr:
+		return "stderr"
+	case StreamSystem:
+		return "system"
+	case StreamStdin:
+		return "stdin"
+	default:
+		return "unknown"
+	}
+}
+
+// LogEntry is a single captured output line with its capture metadata.
+type LogEntry struct {
+	Seq       uint64    // Monotonic per manager, never reused after ClearLogs
+	Time      time.Time // Wall-clock time the line was read from its stream
+	Stream    Stream
+	Iteration int // Loop iteration the line belongs to (0 = outside any)
+	Text      string
+}
+
+// SortByCapture orders entries by capture time, then sequence. stdout and
+// stderr are read concurrently, so a line can reach the buffer after a line
+// from the other stream that was written later.
+func SortByCapture(entries []LogEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].Time.Equal(entries[j].Time) {
+			return entries[i].Time.Before(entries[j].Time)
+		}
+		return entries[i].Seq < entries[j].Seq
+	})
+}
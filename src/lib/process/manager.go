@@ -1,9 +1,10 @@
 package process
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"sync"
 	"syscall"
@@ -13,8 +14,15 @@ import (
 const (
 	// DefaultBufferSize is the default ring buffer size for logs.
 	DefaultBufferSize = 1000
+
+	// DefaultPTYCols and DefaultPTYRows size the PTY until the first Resize.
+	DefaultPTYCols = 80
+	DefaultPTYRows = 24
 )
 
+// ErrNotRunning is returned when a stop targets a process that is not running.
+var ErrNotRunning = errors.New("process not running")
+
 // Status represents the current state of the managed process.
 type Status int
 
@@ -24,6 +32,7 @@ const (
 	StatusStopping
 	StatusStopped
 	StatusPaused
+	StatusFrozen
 )
 
 func (s Status) String() string {
@@ -38,6 +47,8 @@ func (s Status) String() string {
 		return "Stopped"
 	case StatusPaused:
 		return "Paused"
+	case StatusFrozen:
+		return "Frozen"
 	default:
 		return "Unknown"
 	}
@@ -45,21 +56,113 @@ func (s Status) String() string {
 
 // Manager manages a subprocess lifecycle with output streaming.
 type Manager struct {
-	cmd        *exec.Cmd
-	status     Status
-	logs       *RingBuffer
-	doneChan   chan error
-	mu         sync.RWMutex
-	onComplete func() // Callback when process completes naturally
+	cmd          *exec.Cmd
+	status       Status
+	logs         LogStore
+	doneChan     chan struct{} // Closed when the current process exits
+	exitErr      error         // Result of cmd.Wait(), valid once doneChan is closed
+	mu           sync.RWMutex
+	onComplete   func()            // Callback when process completes naturally
+	onOutput     func(line string) // Callback for every captured output line
+	usePTY       bool              // Run under a pseudo-terminal instead of pipes
+	startOpts    StartOptions      // Environment, directory and extra arguments
+	sessionLog   *SessionLog       // Every captured line is also appended here
+	maxLine      int               // Longest line captured as one entry
+	longLines    LongLinePolicy    // What happens to lines longer than maxLine
+	mergeStreams bool              // Pipe mode: stdout and stderr share one pipe
+	escalation   EscalationConfig  // Signal ladders used to take the process down
+	pty          *os.File          // PTY master while a PTY-backed process runs
+	stdin        io.WriteCloser    // Process input (pipe, or the PTY master)
+	cols         int               // Terminal size propagated to the PTY
+	rows         int
+	seq          uint64 // Sequence number of the last captured entry
+	iteration    int    // Iteration stamped on captured entries
+	subs         map[*Subscription]struct{}
+	startedAt    time.Time // When the current process started
+	startSeq     uint64    // Sequence number before the current process's first entry
+	lastExit     *ExitInfo // Outcome of the most recent run
+	stopCause    StopCause // Why the current process is being taken down
+
+	// Pidfile recording the running process, and an orphan taken over from it
+	pidPath string
+	pidMeta PidFile     // Descriptive fields copied into every record
+	pid     *PidFile    // Record of the running process
+	adopted *os.Process // Set instead of cmd by Adopt
+
+	// How far the output file has been captured, for an adopter to resume
+	outputOffset int64     // Bytes of the output file captured
+	outputLogged int       // Session log lines written by then
+	outputSaved  time.Time // When the offset last went to the pidfile
+
+	// Stall detection
+	stallTimeout time.Duration             // Quiet period before a stall (0 = off)
+	lastOutput   time.Time                 // Last output line (or start/thaw)
+	stalled      bool                      // A stall was reported and output has not resumed
+	onStall      func(quiet time.Duration) // Callback when a stall is detected
+
+	// Resource sampling of the process group
+	usageInterval time.Duration // 0 disables sampling
+	usage         []UsageSample // Recent samples, oldest first
 }
 
 // NewManager creates a new process manager with a ring buffer for logs.
 func NewManager(bufferSize int) *Manager {
 	return &Manager{
 		logs:     NewRingBuffer(bufferSize),
-		doneChan: make(chan error, 1),
+		doneChan: make(chan struct{}),
 		status:   StatusIdle,
+		cols:     DefaultPTYCols,
+		rows:     DefaultPTYRows,
+		subs:     make(map[*Subscription]struct{}),
+		maxLine:  DefaultMaxLineLength,
+
+		usageInterval: DefaultUsageInterval,
+	}
+}
+
+// SetPTY selects PTY-backed execution for subsequent starts. Under a PTY the
+// agent sees a terminal and keeps its colors and progress output; stdout and
+// stderr arrive as a single stream. Pipe mode (the default) suits headless use.
+func (m *Manager) SetPTY(enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usePTY = enabled
+}
+
+// SetMergeStreams makes subsequent pipe-mode starts send stderr down the
+// stdout pipe. Lines then keep the exact order the process wrote them in,
+// but are all captured as stdout (as under a PTY).
+func (m *Manager) SetMergeStreams(enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mergeStreams = enabled
+}
+
+// UsesPTY reports whether subsequent starts run under a pseudo-terminal.
+func (m *Manager) UsesPTY() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.usePTY
+}
+
+// Resize records the terminal size for PTY-backed processes and applies it to
+// the running one, which then receives SIGWINCH from the kernel.
+func (m *Manager) Resize(cols, rows int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Guard: Ignore nonsensical sizes
+	if cols <= 0 || rows <= 0 {
+		return fmt.Errorf("invalid terminal size %dx%d", cols, rows)
+	}
+
+	m.cols = cols
+	m.rows = rows
+
+	if m.pty == nil {
+		return nil
 	}
+	return setWinsize(m.pty, cols, rows)
 }
 
 // Start spawns the given command as a subprocess and begins streaming output.
@@ -72,68 +175,160 @@ func (m *Manager) Start(command string, args ...string) error {
 		m.mu.Unlock()
 		return fmt.Errorf("process already running or stopping")
 	}
-
-	// Close old doneChan if it exists to prevent leaks
-	if m.doneChan != nil {
-		select {
-		case <-m.doneChan:
-			// Already drained
-		default:
-			// Not drained, but we're starting fresh
-		}
+	// Guard: Another ralph-tui's loop holds the directory
+	if err := m.checkPidFile(); err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	if err := m.resetOutputFile(); err != nil {
+		m.mu.Unlock()
+		return err
 	}
 
 	// Parse command into trusted state
-	m.cmd = exec.Command(command, args...)
-	m.cmd.SysProcAttr = &syscall.SysProcAttr{
+	opts := m.startOpts
+	cmd := exec.Command(command, append(args[:len(args):len(args)], opts.ExtraArgs...)...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
 		Setpgid: true, // Create process group for clean child termination
 	}
-	m.status = StatusRunning
-	m.doneChan = make(chan error, 1)
-
-	// Capture stdout and stderr
-	stdout, err := m.cmd.StdoutPipe()
-	if err != nil {
-		m.status = StatusIdle
-		m.mu.Unlock()
-		return fmt.Errorf("failed to create stdout pipe: %w", err)
+	cmd.Dir = opts.Dir
+	if opts.customizesEnv() {
+		cmd.Env = opts.Environ(os.Environ())
 	}
 
-	stderr, err := m.cmd.StderrPipe()
-	if err != nil {
-		m.status = StatusIdle
-		m.mu.Unlock()
-		return fmt.Errorf("failed to create stderr pipe: %w", err)
+	// Capture output through a PTY, one pipe shared by stdout and stderr, or
+	// separate pipes; input goes through the PTY or a pipe the manager owns
+	var streams []outputStream
+	var ptyMaster *os.File
+	var merged *os.File // Read end of the shared pipe, closed once drained
+	var stdin io.WriteCloser
+	if m.usePTY {
+		master, err := m.attachPTY(cmd)
+		if err != nil {
+			m.mu.Unlock()
+			return err
+		}
+		ptyMaster = master
+		streams = []outputStream{{master, StreamStdout}}
+		stdin = master
+	} else {
+		pipe, err := cmd.StdinPipe()
+		if err != nil {
+			m.mu.Unlock()
+			return fmt.Errorf("failed to create stdin pipe: %w", err)
+		}
+		stdin = pipe
+
+		if m.mergeStreams {
+			// One pipe keeps the order the process wrote in
+			r, w, err := os.Pipe()
+			if err != nil {
+				m.mu.Unlock()
+				return fmt.Errorf("failed to create output pipe: %w", err)
+			}
+			cmd.Stdout = w
+			cmd.Stderr = w
+			merged = r
+			streams = []outputStream{{r, StreamStdout}}
+		} else {
+			stdout, err := cmd.StdoutPipe()
+			if err != nil {
+				m.mu.Unlock()
+				return fmt.Errorf("failed to create stdout pipe: %w", err)
+			}
+
+			stderr, err := cmd.StderrPipe()
+			if err != nil {
+				m.mu.Unlock()
+				return fmt.Errorf("failed to create stderr pipe: %w", err)
+			}
+			streams = []outputStream{{stdout, StreamStdout}, {stderr, StreamStderr}}
+		}
 	}
 
 	// Start process
-	if err := m.cmd.Start(); err != nil {
-		m.status = StatusIdle
+	if err := cmd.Start(); err != nil {
+		if ptyMaster != nil {
+			ptyMaster.Close()
+			cmd.Stdin.(*os.File).Close()
+		}
+		if merged != nil {
+			merged.Close()
+			cmd.Stdout.(*os.File).Close()
+		}
 		m.mu.Unlock()
 		return fmt.Errorf("failed to start process: %w", err)
 	}
 
+	// The child holds its own copy of the PTY slave or shared pipe
+	if ptyMaster != nil {
+		cmd.Stdin.(*os.File).Close()
+	}
+	if merged != nil {
+		cmd.Stdout.(*os.File).Close()
+	}
+
+	m.cmd = cmd
+	m.adopted = nil
+	m.pty = ptyMaster
+	m.stdin = stdin
+	m.setStatus(StatusRunning)
+	m.doneChan = make(chan struct{})
+	m.exitErr = nil
+	m.startedAt = time.Now()
+	m.startSeq = m.seq
+	m.stopCause = StopNone
+	m.lastOutput = m.startedAt
+	m.stalled = false
+	m.outputOffset = 0
+	m.outputLogged = m.sessionLog.written()
+	doneChan := m.doneChan
+	pidErr := m.writePidFile(newPidFile(cmd.Process.Pid, command, m.startedAt))
 	m.mu.Unlock()
 
+	if pidErr != nil {
+		m.AppendLog(fmt.Sprintf("Failed to record the process: %v", pidErr))
+	}
+
 	// Stream output in background goroutines
 	var wg sync.WaitGroup
-	wg.Add(2)
+	wg.Add(len(streams))
 
-	go m.streamOutput(&wg, stdout, "[OUT]")
-	go m.streamOutput(&wg, stderr, "[ERR]")
+	for _, stream := range streams {
+		go m.streamOutput(&wg, stream.reader, stream.stream)
+	}
+	go m.monitorStall(doneChan)
+	go m.monitorUsage(cmd.Process.Pid, doneChan)
 
 	// Wait for process completion in background
 	go func() {
 		wg.Wait() // Wait for output streams to close
-		err := m.cmd.Wait()
+		err := cmd.Wait()
+
+		if ptyMaster != nil {
+			ptyMaster.Close()
+		}
+		if merged != nil {
+			merged.Close()
+		}
 
 		m.mu.Lock()
-		m.status = StatusStopped
+		m.exitErr = err
+		m.pty = nil
+		m.stdin = nil
+		m.stalled = false
+		info := newExitInfo(err, m.startedAt, time.Now(), m.stopCause)
+		info.Stderr = m.stderrTail(DefaultExitTailLines, ptyMaster != nil || merged != nil)
+		m.lastExit = &info
+		m.clearPidFile()
+		m.publish(Event{Kind: EventExit, Err: err, Exit: info})
+		m.setStatus(StatusStopped)
 		// Copy callback under lock to prevent race
 		callback := m.onComplete
 		m.mu.Unlock()
 
-		m.doneChan <- err
+		// Closing (rather than sending) lets every waiter observe the exit
+		close(doneChan)
 
 		// Trigger completion callback if registered
 		if callback != nil {
@@ -144,207 +339,294 @@ func (m *Manager) Start(command string, args ...string) error {
 	return nil
 }
 
+// attachPTY wires the command's stdio to a new pseudo-terminal sized to the
+// last Resize and returns the master end. Must be called with m.mu held.
+func (m *Manager) attachPTY(cmd *exec.Cmd) (*os.File, error) {
+	master, slave, err := openPTY()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pty: %w", err)
+	}
+
+	if err := setWinsize(master, m.cols, m.rows); err != nil {
+		master.Close()
+		slave.Close()
+		return nil, fmt.Errorf("failed to set pty size: %w", err)
+	}
+
+	cmd.Stdin = slave
+	cmd.Stdout = slave
+	cmd.Stderr = slave
+	// A session leader also leads its own process group, so group signals
+	// keep working; Setpgid is not allowed for session leaders
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setsid:  true,
+		Setctty: true,
+		Ctty:    0,
+	}
+
+	return master, nil
+}
+
+// outputStream pairs a process output reader with the stream it carries.
+type outputStream struct {
+	reader io.Reader
+	stream Stream
+}
+
 // streamOutput reads from the given reader and writes to the ring buffer.
-func (m *Manager) streamOutput(wg *sync.WaitGroup, r io.Reader, prefix string) {
+// Long lines are split or truncated per the line policy; read errors are
+// logged and reading carries on until the stream ends.
+func (m *Manager) streamOutput(wg *sync.WaitGroup, r io.Reader, stream Stream) {
 	defer wg.Done()
 
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		line := scanner.Text()
-		m.logs.Write(fmt.Sprintf("%s %s", prefix, line))
+	m.mu.RLock()
+	reader := newLineReader(r, m.maxLine, m.longLines)
+	// Without a PTY, stdout carries what loop.sh copies to its output file
+	mirrored := stream == StreamStdout && m.pty == nil
+	m.mu.RUnlock()
+
+	failures := 0
+	for {
+		line, err := reader.next()
+		captured := time.Now()
+		// A PTY reports EIO along with a final line that has no newline
+		if line != "" || err == nil {
+			var offset int64
+			if mirrored {
+				offset = reader.consumed()
+			}
+			m.capture(stream, line, captured, offset)
+		}
+		if err == nil {
+			failures = 0
+			continue
+		}
+		if isEndOfStream(err) {
+			return
+		}
+
+		// Guard: A stream that keeps failing will not recover
+		failures++
+		m.AppendLog(fmt.Sprintf("Failed to read %s: %v", stream, err))
+		if failures >= maxReadErrors {
+			m.AppendLog(fmt.Sprintf("Giving up on %s after %d read errors", stream, failures))
+			return
+		}
 	}
 }
 
-// Stop sends SIGTERM to the process and waits up to 5 seconds before sending SIGKILL.
-func (m *Manager) Stop() error {
+// capture stores a line of process output and hands it to the output
+// callback. A positive offset is where the line ends in the output file.
+func (m *Manager) capture(stream Stream, line string, captured time.Time, offset int64) {
 	m.mu.Lock()
-
-	// Guard: Cannot stop if not running or already stopping
-	if m.status != StatusRunning && m.status != StatusStopping {
-		m.mu.Unlock()
-		return fmt.Errorf("process not running")
+	m.recordLocked(stream, line, captured)
+	// Under the same lock, so the pidfile's offset and log count agree
+	if offset > 0 {
+		m.advanceOutput(offset)
 	}
+	callback := m.onOutput
+	m.mu.Unlock()
+	if callback != nil {
+		callback(line)
+	}
+}
 
-	if m.cmd == nil || m.cmd.Process == nil {
-		m.mu.Unlock()
-		return fmt.Errorf("no process to stop")
+// record stamps a line with its capture metadata and stores it. Sequence
+// numbers are assigned under the lock so buffer order matches Seq order.
+func (m *Manager) record(stream Stream, text string) {
+	m.recordAt(stream, text, time.Now())
+}
+
+// recordAt is record with the time the line was read. Streams are read
+// concurrently, so the capture time orders lines across streams better than
+// the order they reach the buffer in.
+func (m *Manager) recordAt(stream Stream, text string, captured time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recordLocked(stream, text, captured)
+}
+
+// recordLocked is recordAt for callers holding m.mu.
+func (m *Manager) recordLocked(stream Stream, text string, captured time.Time) {
+	m.seq++
+	entry := LogEntry{
+		Seq:       m.seq,
+		Time:      captured,
+		Stream:    stream,
+		Iteration: m.iteration,
+		Text:      text,
+	}
+	m.logs.Write(entry)
+	if m.sessionLog != nil {
+		m.sessionLog.Write(entry)
 	}
+	m.publish(Event{Kind: EventLog, Entry: entry})
 
-	m.status = StatusStopping
-	process := m.cmd.Process
-	doneChan := m.doneChan
-	m.mu.Unlock()
+	// Annotations and forwarded input are not signs of life from the process
+	if stream == StreamStdout || stream == StreamStderr {
+		m.markOutput(entry.Time)
+	}
+}
 
-	// Send SIGTERM to process group for graceful shutdown
-	pgid := process.Pid
-	if err := syscall.Kill(-pgid, syscall.SIGTERM); err != nil {
-		// Handle ESRCH (no such process) gracefully
-		if err == syscall.ESRCH {
-			return nil
-		}
-		// Fallback to single process if process group kill fails
-		if err := process.Signal(syscall.SIGTERM); err != nil {
-			if err == syscall.ESRCH {
-				return nil
+// stderrTail returns the last n stderr lines of the current run. A PTY merges
+// both streams, so its combined output is used instead. Must be called with
+// m.mu held.
+func (m *Manager) stderrTail(n int, merged bool) []string {
+	var tail []string
+
+	// Walk back in pages: the run's output may reach into spilled scrollback
+	const page = 256
+	for end := m.logs.Size(); end > 0 && len(tail) < n; end -= page {
+		entries := m.logs.ReadRange(end-page, page)
+		for i := len(entries) - 1; i >= 0 && len(tail) < n; i-- {
+			entry := entries[i]
+			if entry.Seq <= m.startSeq {
+				end = 0
+				break
+			}
+			if entry.Stream == StreamStderr || (merged && entry.Stream == StreamStdout) {
+				tail = append(tail, entry.Text)
 			}
-			return fmt.Errorf("failed to send SIGTERM: %w", err)
 		}
 	}
 
-	// Wait for graceful shutdown with timeout
-	select {
-	case <-doneChan:
-		return nil
-	case <-time.After(5 * time.Second):
-		// Timeout - force kill process group
-		if err := syscall.Kill(-pgid, syscall.SIGKILL); err != nil {
-			// Handle ESRCH gracefully
-			if err != syscall.ESRCH {
-				// Fallback to single process kill
-				if err := process.Kill(); err != nil && err != syscall.ESRCH {
-					return fmt.Errorf("failed to kill process: %w", err)
-				}
-			}
-		}
-		return fmt.Errorf("process killed after timeout")
+	// Collected newest first
+	for i, j := 0, len(tail)-1; i < j; i, j = i+1, j-1 {
+		tail[i], tail[j] = tail[j], tail[i]
 	}
+	return tail
 }
 
-// StopImmediate sends SIGINT to the process for immediate interruption (<2s).
-func (m *Manager) StopImmediate() error {
-	m.mu.Lock()
-
-	// Guard: Cannot stop if not running or already stopping
-	if m.status != StatusRunning && m.status != StatusStopping {
-		m.mu.Unlock()
-		return fmt.Errorf("process not running")
+// setStatus changes the status and notifies subscribers. Must be called with
+// m.mu held.
+func (m *Manager) setStatus(status Status) {
+	if m.status == status {
+		return
 	}
+	m.status = status
+	m.publish(Event{Kind: EventStatus, Status: status})
+}
 
-	if m.cmd == nil || m.cmd.Process == nil {
-		m.mu.Unlock()
-		return fmt.Errorf("no process to stop")
+// publish delivers an event to every subscriber. Must be called with m.mu
+// held so events are queued in the order they happened.
+func (m *Manager) publish(ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	for sub := range m.subs {
+		sub.publish(ev)
 	}
+}
 
-	m.status = StatusStopping
-	process := m.cmd.Process
-	doneChan := m.doneChan
+// Subscribe returns a subscription receiving every log entry and lifecycle
+// event from now on. Callers must Unsubscribe when done.
+func (m *Manager) Subscribe() *Subscription {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sub := newSubscription()
+	m.subs[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe stops delivery to a subscription and closes its channel.
+func (m *Manager) Unsubscribe(sub *Subscription) {
+	m.mu.Lock()
+	delete(m.subs, sub)
 	m.mu.Unlock()
 
-	// Send SIGINT to process group for immediate stop
-	pgid := process.Pid
-	if err := syscall.Kill(-pgid, syscall.SIGINT); err != nil {
-		// Handle ESRCH (no such process) gracefully
-		if err == syscall.ESRCH {
-			return nil
-		}
-		// Fallback to single process if process group kill fails
-		if err := process.Signal(syscall.SIGINT); err != nil {
-			if err == syscall.ESRCH {
-				return nil
-			}
-			return fmt.Errorf("failed to send SIGINT: %w", err)
-		}
+	sub.Close()
+}
+
+// Publish delivers a loop event to subscribers alongside the process's own
+// events. Used by loop runners to report iteration boundaries.
+func (m *Manager) Publish(ev Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.publish(ev)
+}
+
+// Freeze suspends the whole process group with SIGSTOP. Unlike Stop, the
+// in-flight iteration is kept and carries on exactly where it was on Thaw.
+func (m *Manager) Freeze() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Guard: Can only freeze a running process
+	if m.status != StatusRunning {
+		return ErrNotRunning
 	}
 
-	// Wait for immediate stop with 2 second timeout
-	select {
-	case <-doneChan:
-		return nil
-	case <-time.After(2 * time.Second):
-		// Timeout - force kill process group
-		if err := syscall.Kill(-pgid, syscall.SIGKILL); err != nil {
-			// Handle ESRCH gracefully
-			if err != syscall.ESRCH {
-				// Fallback to single process kill
-				if err := process.Kill(); err != nil && err != syscall.ESRCH {
-					return fmt.Errorf("failed to kill process: %w", err)
-				}
-			}
-		}
-		return fmt.Errorf("process killed after timeout")
+	if m.process() == nil {
+		return fmt.Errorf("no process to freeze")
+	}
+
+	if err := signalGroup(m.process(), syscall.SIGSTOP); err != nil {
+		return fmt.Errorf("failed to send SIGSTOP: %w", err)
 	}
+	m.setStatus(StatusFrozen)
+
+	return nil
 }
 
-// Pause stops the process gracefully and transitions to paused state.
-// This allows manual restart later.
-func (m *Manager) Pause() error {
+// Thaw continues a frozen process group with SIGCONT.
+func (m *Manager) Thaw() error {
 	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	// Guard: Cannot pause if not running
-	if m.status != StatusRunning {
-		m.mu.Unlock()
-		return fmt.Errorf("process not running")
+	// Guard: Can only thaw a frozen process
+	if m.status != StatusFrozen {
+		return fmt.Errorf("process not frozen")
 	}
 
-	if m.cmd == nil || m.cmd.Process == nil {
-		m.mu.Unlock()
-		return fmt.Errorf("no process to pause")
+	if err := signalGroup(m.process(), syscall.SIGCONT); err != nil {
+		return fmt.Errorf("failed to send SIGCONT: %w", err)
 	}
+	m.setStatus(StatusRunning)
+	// Time spent frozen does not count towards a stall
+	m.markOutput(time.Now())
 
-	m.status = StatusStopping
-	process := m.cmd.Process
-	doneChan := m.doneChan
-	m.mu.Unlock()
+	return nil
+}
 
-	// Send SIGTERM to process group for graceful shutdown
-	pgid := process.Pid
-	if err := syscall.Kill(-pgid, syscall.SIGTERM); err != nil {
-		// Handle ESRCH (no such process) gracefully
+// signalGroup sends sig to the process group led by process, falling back to
+// the single process. ESRCH (already gone) is not an error.
+func signalGroup(process *os.Process, sig syscall.Signal) error {
+	if err := syscall.Kill(-process.Pid, sig); err != nil {
 		if err == syscall.ESRCH {
-			m.mu.Lock()
-			m.status = StatusPaused
-			m.mu.Unlock()
 			return nil
 		}
-		// Fallback to single process if process group kill fails
-		if err := process.Signal(syscall.SIGTERM); err != nil {
-			if err == syscall.ESRCH {
-				m.mu.Lock()
-				m.status = StatusPaused
-				m.mu.Unlock()
-				return nil
-			}
-			return fmt.Errorf("failed to send SIGTERM: %w", err)
+		if err := process.Signal(sig); err != nil && err != syscall.ESRCH && err != os.ErrProcessDone {
+			return err
 		}
 	}
+	return nil
+}
 
-	// Wait for graceful shutdown with timeout
-	select {
-	case <-doneChan:
-		m.mu.Lock()
-		m.status = StatusPaused
-		m.mu.Unlock()
+// process returns the running process: the started command or an adopted
+// orphan. Must be called with m.mu held.
+func (m *Manager) process() *os.Process {
+	if m.adopted != nil {
+		return m.adopted
+	}
+	if m.cmd == nil {
 		return nil
-	case <-time.After(5 * time.Second):
-		// Timeout - force kill process group
-		if err := syscall.Kill(-pgid, syscall.SIGKILL); err != nil {
-			// Handle ESRCH gracefully
-			if err != syscall.ESRCH {
-				// Fallback to single process kill
-				if err := process.Kill(); err != nil && err != syscall.ESRCH {
-					return fmt.Errorf("failed to kill process: %w", err)
-				}
-			}
-		}
-		m.mu.Lock()
-		m.status = StatusPaused
-		m.mu.Unlock()
-		return fmt.Errorf("process killed after timeout")
 	}
+	return m.cmd.Process
 }
 
-// IsRunning returns true if the process is currently running.
+// IsRunning returns true if the process is currently running (not frozen).
 func (m *Manager) IsRunning() bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	return m.status == StatusRunning
 }
 
-// IsPaused returns true if the process is paused.
-func (m *Manager) IsPaused() bool {
+// IsFrozen returns true if the process group is suspended by Freeze.
+func (m *Manager) IsFrozen() bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	return m.status == StatusPaused
+	return m.status == StatusFrozen
 }
 
 // GetStatus returns the current status of the process.
@@ -354,14 +636,83 @@ func (m *Manager) GetStatus() Status {
 	return m.status
 }
 
-// GetLogs returns all current log lines.
-func (m *Manager) GetLogs() []string {
-	return m.logs.ReadAll()
+// LastExit returns the outcome of the most recent run; false if no process
+// has exited yet.
+func (m *Manager) LastExit() (ExitInfo, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.lastExit == nil {
+		return ExitInfo{}, false
+	}
+	return *m.lastExit, true
+}
+
+// GetLogs returns all current log entries.
+func (m *Manager) GetLogs() []LogEntry {
+	return m.logStore().ReadAll()
+}
+
+// TailLogs returns up to the n most recent log entries.
+func (m *Manager) TailLogs(n int) []LogEntry {
+	return m.logStore().ReadLast(n)
+}
+
+// LogCount returns how many log entries are held.
+func (m *Manager) LogCount() int {
+	return m.logStore().Size()
+}
+
+// ReadLogs returns up to n log entries starting at index start, where 0 is
+// the oldest entry held. Used to scroll back through the session.
+func (m *Manager) ReadLogs(start, n int) []LogEntry {
+	return m.logStore().ReadRange(start, n)
+}
+
+// SetLogStore replaces the log buffer, e.g. with a SpillBuffer for unbounded
+// scrollback. Entries already captured are not carried over.
+func (m *Manager) SetLogStore(store LogStore) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logs = store
+}
+
+// logStore returns the current log buffer.
+func (m *Manager) logStore() LogStore {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.logs
+}
+
+// SetIteration sets the loop iteration stamped on subsequent log entries.
+func (m *Manager) SetIteration(iteration int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.iteration = iteration
+
+	// Keep the pidfile's iteration current for a later adopter
+	if m.pid != nil && m.pid.Iteration != iteration {
+		_ = m.writePidFile(*m.pid)
+	}
 }
 
 // ClearLogs empties the log buffer.
 func (m *Manager) ClearLogs() {
-	m.logs.Clear()
+	m.logStore().Clear()
+}
+
+// AppendLog writes a system line to the log buffer without it coming from
+// the process. Used by loop runners to record iteration boundaries and other
+// annotations.
+func (m *Manager) AppendLog(line string) {
+	m.record(StreamSystem, line)
+}
+
+// OnOutput registers a callback invoked with every raw line the process writes
+// to stdout or stderr. The callback runs on the streaming goroutine.
+func (m *Manager) OnOutput(fn func(line string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onOutput = fn
 }
 
 // OnComplete registers a callback to invoke when the process completes.
@@ -372,6 +723,15 @@ func (m *Manager) OnComplete(fn func()) {
 }
 
 // WaitForExit blocks until the process exits and returns the error (if any).
+// Safe to call from multiple goroutines and after the process has exited.
 func (m *Manager) WaitForExit() error {
-	return <-m.doneChan
+	m.mu.RLock()
+	doneChan := m.doneChan
+	m.mu.RUnlock()
+
+	<-doneChan
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.exitErr
 }
@@ -1,7 +1,9 @@
 package process
 
 import (
+	"runtime"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -91,8 +93,8 @@ func TestManager_ProcessOutputCapture(t *testing.T) {
 	// Check logs contain output
 	logs := mgr.GetLogs()
 	found := false
-	for _, line := range logs {
-		if strings.Contains(line, "test output") {
+	for _, entry := range logs {
+		if strings.Contains(entry.Text, "test output") {
 			found = true
 			break
 		}
@@ -222,3 +224,363 @@ func TestManager_RestartAfterStop(t *testing.T) {
 	// Clean up
 	_ = mgr.Stop()
 }
+
+func TestManager_FreezeThaw(t *testing.T) {
+	mgr := NewManager(DefaultBufferSize)
+
+	// Emit a line every 20ms so a frozen group is observable via the logs
+	err := mgr.Start("sh", "-c", "for i in 1 2 3 4 5 6 7 8 9 10; do echo tick; sleep 0.02; done")
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	if err := mgr.Freeze(); err != nil {
+		t.Fatalf("Failed to freeze process: %v", err)
+	}
+	if mgr.GetStatus() != StatusFrozen {
+		t.Errorf("Expected StatusFrozen, got %v", mgr.GetStatus())
+	}
+
+	// Let any in-flight output drain, then verify nothing new arrives
+	time.Sleep(50 * time.Millisecond)
+	frozenCount := len(mgr.GetLogs())
+	time.Sleep(150 * time.Millisecond)
+	if count := len(mgr.GetLogs()); count != frozenCount {
+		t.Errorf("Expected no output while frozen, got %d new lines", count-frozenCount)
+	}
+
+	if err := mgr.Thaw(); err != nil {
+		t.Fatalf("Failed to thaw process: %v", err)
+	}
+	if !mgr.IsRunning() {
+		t.Error("Expected process to be running after thaw")
+	}
+
+	if err := mgr.WaitForExit(); err != nil {
+		t.Errorf("Expected thawed process to finish cleanly: %v", err)
+	}
+	if count := len(mgr.GetLogs()); count != 10 {
+		t.Errorf("Expected all 10 lines after thaw, got %d", count)
+	}
+}
+
+func TestManager_StopFrozenProcess(t *testing.T) {
+	mgr := NewManager(DefaultBufferSize)
+
+	err := mgr.Start("sleep", "30")
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+
+	if err := mgr.Freeze(); err != nil {
+		t.Fatalf("Failed to freeze process: %v", err)
+	}
+
+	// SIGTERM must be delivered without waiting for the SIGKILL timeout
+	start := time.Now()
+	if err := mgr.Stop(); err != nil {
+		t.Fatalf("Failed to stop frozen process: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected prompt stop of frozen process, took %v", elapsed)
+	}
+
+	if err := mgr.Thaw(); err == nil {
+		t.Error("Expected thaw to fail after stop")
+	}
+}
+
+func TestManager_PTYMode(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("pty mode is only supported on Linux")
+	}
+
+	mgr := NewManager(DefaultBufferSize)
+	mgr.SetPTY(true)
+
+	if err := mgr.Resize(100, 30); err != nil {
+		t.Fatalf("Failed to set size: %v", err)
+	}
+
+	// The child should see a terminal of the configured size, then the resize
+	err := mgr.Start("sh", "-c", "test -t 1 && echo 'is a tty'; stty size; sleep 0.3; stty size")
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if err := mgr.Resize(120, 40); err != nil {
+		t.Fatalf("Failed to resize running pty: %v", err)
+	}
+
+	if err := mgr.WaitForExit(); err != nil {
+		t.Fatalf("Process exited with error: %v", err)
+	}
+
+	var lines []string
+	for _, entry := range mgr.GetLogs() {
+		lines = append(lines, entry.Text)
+	}
+	logs := strings.Join(lines, "\n")
+	for _, want := range []string{"is a tty", "30 100", "40 120"} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("Expected logs to contain %q, got:\n%s", want, logs)
+		}
+	}
+	if strings.Contains(logs, "\r") {
+		t.Error("Expected carriage returns to be stripped from pty lines")
+	}
+}
+
+func TestManager_PTYPartialLastLine(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("pty mode is only supported on Linux")
+	}
+
+	mgr := NewManager(DefaultBufferSize)
+	mgr.SetPTY(true)
+
+	// The last line has no newline; the read that returns it also gets EIO
+	if err := mgr.Start("printf", "first\\nContinue? [y/n] "); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	if err := mgr.WaitForExit(); err != nil {
+		t.Fatalf("Process exited with error: %v", err)
+	}
+
+	logs := mgr.GetLogs()
+	if len(logs) == 0 || logs[len(logs)-1].Text != "Continue? [y/n] " {
+		t.Errorf("Expected the partial last line captured, got %+v", logs)
+	}
+}
+
+func TestManager_PTYStop(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("pty mode is only supported on Linux")
+	}
+
+	mgr := NewManager(DefaultBufferSize)
+	mgr.SetPTY(true)
+
+	err := mgr.Start("sh", "-c", "sleep 30 & wait")
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	err = mgr.Stop()
+	if err != nil && !strings.Contains(err.Error(), "killed after timeout") {
+		t.Fatalf("Failed to stop process: %v", err)
+	}
+	if mgr.IsRunning() {
+		t.Error("Expected process to be stopped")
+	}
+}
+
+func TestManager_LogEntryMetadata(t *testing.T) {
+	mgr := NewManager(DefaultBufferSize)
+	mgr.SetIteration(3)
+	mgr.AppendLog("annotation")
+
+	before := time.Now()
+	err := mgr.Start("sh", "-c", "echo out; echo err >&2")
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	_ = mgr.WaitForExit()
+
+	logs := mgr.GetLogs()
+	if len(logs) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(logs))
+	}
+
+	streams := map[string]Stream{}
+	for i, entry := range logs {
+		streams[entry.Text] = entry.Stream
+
+		if entry.Iteration != 3 {
+			t.Errorf("Expected iteration 3 on %q, got %d", entry.Text, entry.Iteration)
+		}
+		if i > 0 && entry.Seq <= logs[i-1].Seq {
+			t.Errorf("Expected increasing sequence numbers, got %d after %d", entry.Seq, logs[i-1].Seq)
+		}
+		if entry.Text != "annotation" && entry.Time.Before(before) {
+			t.Errorf("Expected capture time after start for %q", entry.Text)
+		}
+	}
+
+	if streams["annotation"] != StreamSystem || streams["out"] != StreamStdout || streams["err"] != StreamStderr {
+		t.Errorf("Unexpected streams: %v", streams)
+	}
+
+	// Sequence numbers keep increasing after a clear
+	last := logs[len(logs)-1].Seq
+	mgr.ClearLogs()
+	mgr.AppendLog("after clear")
+	if seq := mgr.GetLogs()[0].Seq; seq <= last {
+		t.Errorf("Expected sequence to continue after clear, got %d <= %d", seq, last)
+	}
+}
+
+func TestManager_MergeStreamsKeepsOrder(t *testing.T) {
+	mgr := NewManager(DefaultBufferSize)
+	mgr.SetMergeStreams(true)
+
+	// Unbuffered writes that two separate pipes could easily reorder
+	err := mgr.Start("sh", "-c", "for i in 1 2 3 4 5; do echo out$i; echo err$i >&2; done; exit 1")
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	_ = mgr.WaitForExit()
+
+	var texts []string
+	for _, entry := range mgr.GetLogs() {
+		if entry.Stream != StreamStdout {
+			t.Errorf("Expected merged output captured as stdout, got %v for %q", entry.Stream, entry.Text)
+		}
+		texts = append(texts, entry.Text)
+	}
+	want := "out1 err1 out2 err2 out3 err3 out4 err4 out5 err5"
+	if got := strings.Join(texts, " "); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	// The exit summary falls back to the merged output
+	exit, _ := mgr.LastExit()
+	if len(exit.Stderr) == 0 || exit.Stderr[len(exit.Stderr)-1] != "err5" {
+		t.Errorf("Expected merged tail in exit info, got %v", exit.Stderr)
+	}
+}
+
+func TestSortByCapture(t *testing.T) {
+	base := time.Now()
+	entries := []LogEntry{
+		{Seq: 1, Time: base.Add(2 * time.Millisecond), Text: "late"},
+		{Seq: 2, Time: base, Text: "early"},
+		{Seq: 4, Time: base.Add(time.Millisecond), Text: "tie-b"},
+		{Seq: 3, Time: base.Add(time.Millisecond), Text: "tie-a"},
+	}
+
+	SortByCapture(entries)
+
+	var texts []string
+	for _, entry := range entries {
+		texts = append(texts, entry.Text)
+	}
+	if got := strings.Join(texts, " "); got != "early tie-a tie-b late" {
+		t.Errorf("Unexpected order: %s", got)
+	}
+}
+
+func TestManager_LastExitCodeAndStderr(t *testing.T) {
+	mgr := NewManager(DefaultBufferSize)
+
+	if _, ok := mgr.LastExit(); ok {
+		t.Error("Expected no exit info before any run")
+	}
+
+	err := mgr.Start("sh", "-c", "echo out; echo err1 >&2; echo err2 >&2; exit 3")
+	if err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	_ = mgr.WaitForExit()
+
+	exit, ok := mgr.LastExit()
+	if !ok {
+		t.Fatal("Expected exit info after run")
+	}
+	if exit.Code != 3 || exit.Signaled() || exit.Requested {
+		t.Errorf("Expected plain exit 3, got %+v", exit)
+	}
+	if exit.String() != "crashed with 3" {
+		t.Errorf("Expected 'crashed with 3', got %q", exit.String())
+	}
+	if strings.Join(exit.Stderr, ",") != "err1,err2" {
+		t.Errorf("Expected stderr tail [err1 err2], got %v", exit.Stderr)
+	}
+	if exit.Duration() <= 0 {
+		t.Errorf("Expected positive duration, got %v", exit.Duration())
+	}
+}
+
+func TestManager_LastExitSignal(t *testing.T) {
+	mgr := NewManager(DefaultBufferSize)
+
+	if err := mgr.Start("sh", "-c", "kill -KILL $$"); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	_ = mgr.WaitForExit()
+
+	exit, _ := mgr.LastExit()
+	if exit.Signal != syscall.SIGKILL || exit.Code != -1 {
+		t.Errorf("Expected SIGKILL with code -1, got %+v", exit)
+	}
+	if exit.String() != "killed by SIGKILL" {
+		t.Errorf("Expected 'killed by SIGKILL', got %q", exit.String())
+	}
+}
+
+func TestManager_LastExitOperatorStop(t *testing.T) {
+	mgr := NewManager(DefaultBufferSize)
+
+	if err := mgr.Start("sleep", "10"); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	if err := mgr.Stop(); err != nil {
+		t.Fatalf("Failed to stop process: %v", err)
+	}
+
+	exit, _ := mgr.LastExit()
+	if !exit.Requested || exit.Signal != syscall.SIGTERM {
+		t.Errorf("Expected operator stop by SIGTERM, got %+v", exit)
+	}
+	if exit.String() != "stopped by SIGTERM" {
+		t.Errorf("Expected 'stopped by SIGTERM', got %q", exit.String())
+	}
+}
+
+func TestManager_EscalateThroughLadder(t *testing.T) {
+	mgr := NewManager(DefaultBufferSize)
+
+	// Ignore the polite signals so only SIGKILL ends it
+	if err := mgr.Start("sh", "-c", "trap '' INT TERM; echo ready; while :; do sleep 0.05; done"); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	err := mgr.Escalate([]SignalStep{
+		{Signal: syscall.SIGINT, Wait: 200 * time.Millisecond},
+		{Signal: syscall.SIGTERM, Wait: 200 * time.Millisecond},
+		{Signal: syscall.SIGKILL, Wait: 2 * time.Second},
+	}, StopTimeout)
+	if err != nil {
+		t.Fatalf("Escalation failed: %v", err)
+	}
+
+	exit, _ := mgr.LastExit()
+	if exit.Signal != syscall.SIGKILL {
+		t.Errorf("Expected process to end by SIGKILL, got %+v", exit)
+	}
+
+	// A timeout is not mistaken for the operator's stop
+	if exit.Requested || exit.Cause != StopTimeout {
+		t.Errorf("Expected timeout cause without operator request, got %+v", exit)
+	}
+	if exit.String() != "timed out, stopped by SIGKILL" {
+		t.Errorf("Expected 'timed out, stopped by SIGKILL', got %q", exit.String())
+	}
+
+	var logs []string
+	for _, entry := range mgr.GetLogs() {
+		logs = append(logs, entry.Text)
+	}
+	joined := strings.Join(logs, "\n")
+	for _, sig := range []string{"SIGINT", "SIGTERM", "SIGKILL"} {
+		if !strings.Contains(joined, "Sending "+sig) {
+			t.Errorf("Expected escalation step %s in logs, got:\n%s", sig, joined)
+		}
+	}
+}
@@ -0,0 +1,464 @@
+package process
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// orphanPollInterval is how often an adopted or killed orphan is checked.
+const orphanPollInterval = 250 * time.Millisecond
+
+// ErrAdoptedExit is the exit error of an adopted process: it is not our
+// child, so its exit status cannot be collected.
+var ErrAdoptedExit = errors.New("exit status of an adopted process is unknown")
+
+// PidFile records a running loop process so that a later ralph-tui can tell
+// whether it outlived the one that started it. It doubles as a lock: a
+// manager refuses to start while the file names another owner's live
+// process.
+type PidFile struct {
+	PID        int       `json:"pid"`  // Group leader: loop.sh or the agent
+	PGID       int       `json:"pgid"` // Equal to PID; signals go to the group
+	StartTicks uint64    `json:"start_ticks"`
+	StartedAt  time.Time `json:"started_at"`
+	Owner      int       `json:"owner"` // ralph-tui process that started it
+	OwnerTicks uint64    `json:"owner_ticks"`
+	Mode       string    `json:"mode,omitempty"`
+	Runner     string    `json:"runner,omitempty"`
+	Script     string    `json:"script"` // Command that was started
+	Dir        string    `json:"dir,omitempty"`
+	Iteration  int       `json:"iteration"`
+	LogDir     string    `json:"log_dir,omitempty"` // Session log of the owner
+	Session    string    `json:"session,omitempty"`
+	Output     string    `json:"output,omitempty"` // File the process copies its output to
+	Offset     int64     `json:"offset,omitempty"` // Bytes of Output the owner captured
+	Logged     int       `json:"logged,omitempty"` // Session log lines the owner had written by then
+}
+
+// ReadPidFile reads the pidfile at path. A missing file returns an error
+// satisfying os.IsNotExist.
+func ReadPidFile(path string) (PidFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return PidFile{}, err
+	}
+	var p PidFile
+	if err := json.Unmarshal(data, &p); err != nil {
+		return PidFile{}, fmt.Errorf("malformed pidfile %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// write replaces the pidfile at path atomically.
+func (p PidFile) write(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create pidfile directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write pidfile: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write pidfile: %w", err)
+	}
+	return nil
+}
+
+// Running reports whether the loop process or anything left in its process
+// group is still alive. A PID reused by an unrelated process does not count.
+func (p PidFile) Running() bool {
+	if st, err := readProcStat(procRoot, p.PID); err == nil {
+		if st.Start == p.StartTicks && st.State != 'Z' {
+			return true
+		}
+		// The PID now belongs to someone else, who may also lead the group
+		if st.PGID == p.PGID {
+			return false
+		}
+	}
+	group, err := listGroup(procRoot, p.PGID)
+	if err != nil {
+		return false
+	}
+	for _, st := range group {
+		if st.State != 'Z' {
+			return true
+		}
+	}
+	return false
+}
+
+// OwnerRunning reports whether the ralph-tui that wrote the pidfile is still
+// alive, i.e. the process is not an orphan.
+func (p PidFile) OwnerRunning() bool {
+	st, err := readProcStat(procRoot, p.Owner)
+	return err == nil && st.Start == p.OwnerTicks && st.State != 'Z'
+}
+
+// Started returns how long ago the process started.
+func (p PidFile) Started() time.Duration {
+	return time.Since(p.StartedAt).Round(time.Second)
+}
+
+// KillOrphan walks the ladder on an orphan's process group until nothing
+// in it is left. Returns an error if it outlives the last step.
+func KillOrphan(p PidFile, policy EscalationPolicy) error {
+	for _, step := range policy {
+		if !p.Running() {
+			return nil
+		}
+		if err := syscall.Kill(-p.PGID, step.Signal); err != nil && err != syscall.ESRCH {
+			return fmt.Errorf("failed to send %s to process group %d: %w", SignalName(step.Signal), p.PGID, err)
+		}
+		for deadline := time.Now().Add(step.Wait); time.Now().Before(deadline); time.Sleep(orphanPollInterval) {
+			if !p.Running() {
+				return nil
+			}
+		}
+	}
+	if p.Running() {
+		return fmt.Errorf("process group %d still running after %d signals", p.PGID, len(policy))
+	}
+	return nil
+}
+
+// RemovePidFile deletes the pidfile at path if it still describes pid.
+func RemovePidFile(path string, pid int) error {
+	p, err := ReadPidFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if p.PID != pid {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove pidfile: %w", err)
+	}
+	return nil
+}
+
+// SetPidFile makes subsequent starts record the process in path; an empty
+// path turns the pidfile off. Mode, Runner, Dir and Output of meta are
+// copied into every record. Output names a file the process copies its
+// output to (loop.sh does); it outlives this ralph-tui's pipes, so an
+// adopter can follow it.
+func (m *Manager) SetPidFile(path string, meta PidFile) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pidPath = path
+	m.pidMeta = meta
+}
+
+// checkPidFile refuses to start while the pidfile names a live process of
+// another ralph-tui. Must be called with m.mu held.
+func (m *Manager) checkPidFile() error {
+	if m.pidPath == "" {
+		return nil
+	}
+	p, err := ReadPidFile(m.pidPath)
+	if err != nil {
+		// A missing or unreadable pidfile does not hold a lock
+		return nil
+	}
+	if p.Owner != os.Getpid() && p.Running() {
+		return fmt.Errorf("another loop (pid %d, started by ralph-tui %d) is running in this directory", p.PID, p.Owner)
+	}
+	return nil
+}
+
+// writePidFile records the process that was just started or adopted. Must
+// be called with m.mu held.
+func (m *Manager) writePidFile(p PidFile) error {
+	if m.pidPath == "" {
+		return nil
+	}
+	p.Mode = m.pidMeta.Mode
+	p.Runner = m.pidMeta.Runner
+	p.Dir = m.pidMeta.Dir
+	p.Output = m.pidMeta.Output
+	p.Offset = m.outputOffset
+	p.Logged = m.outputLogged
+	p.Iteration = m.iteration
+	if m.sessionLog != nil {
+		p.LogDir = m.sessionLog.cfg.Dir
+		p.Session = m.sessionLog.Name()
+	}
+	if err := p.write(m.pidPath); err != nil {
+		return err
+	}
+	m.pid = &p
+	return nil
+}
+
+// clearPidFile removes the record of a process that has exited. Must be
+// called with m.mu held.
+func (m *Manager) clearPidFile() {
+	if m.pid == nil {
+		return
+	}
+	_ = RemovePidFile(m.pidPath, m.pid.PID)
+	m.pid = nil
+}
+
+// resetOutputFile empties the output file for the process about to start,
+// which appends to it. Must be called with m.mu held.
+func (m *Manager) resetOutputFile() error {
+	if m.pidMeta.Output == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(m.pidMeta.Output), 0o755); err != nil {
+		return fmt.Errorf("failed to create output file directory: %w", err)
+	}
+	file, err := os.Create(m.pidMeta.Output)
+	if err != nil {
+		return fmt.Errorf("failed to reset output file: %w", err)
+	}
+	return file.Close()
+}
+
+// advanceOutput records that the output file has been captured up to
+// offset. The pidfile is updated now and then; an adopter resuming from an
+// older record drops the session log lines written since, so nothing is
+// repeated. Must be called with m.mu held.
+func (m *Manager) advanceOutput(offset int64) {
+	if m.pidMeta.Output == "" {
+		return
+	}
+	m.outputOffset = offset
+	m.outputLogged = m.sessionLog.written()
+	if m.pid != nil && time.Since(m.outputSaved) >= orphanPollInterval {
+		_ = m.writePidFile(*m.pid)
+		m.outputSaved = time.Now()
+	}
+}
+
+// newPidFile describes a process this ralph-tui just started.
+func newPidFile(pid int, command string, startedAt time.Time) PidFile {
+	p := PidFile{
+		PID:       pid,
+		PGID:      pid,
+		StartedAt: startedAt,
+		Owner:     os.Getpid(),
+		Script:    command,
+	}
+	if st, err := readProcStat(procRoot, pid); err == nil {
+		p.StartTicks = st.Start
+	}
+	if st, err := readProcStat(procRoot, p.Owner); err == nil {
+		p.OwnerTicks = st.Start
+	}
+	return p
+}
+
+// Adopt takes over a loop process left running by a ralph-tui that died.
+// It is monitored until its process group is gone and can be stopped,
+// frozen and signalled like a started one. The session log the old owner
+// kept is loaded into the log buffer up to where the pidfile's offset into
+// the process's output file was recorded; the output file is followed from
+// there until it exits. Without an output file (the native runner's agent)
+// output written since the old owner died is not captured.
+func (m *Manager) Adopt(p PidFile) error {
+	// Replay the history before taking the lock; it may be long
+	var history []LogEntry
+	if p.LogDir != "" && p.Session != "" {
+		if info, err := FindSession(p.LogDir, p.Session); err == nil {
+			history, _ = ReadSession(info)
+		}
+	}
+
+	var tail *outputTail
+	var tailErr error
+	if p.Output != "" {
+		tail, tailErr = openOutputTail(p.Output, p.Offset)
+	}
+	// The output file repeats what the old owner logged after the offset
+	if tail != nil {
+		history = trimHistory(history, p.Logged)
+	}
+
+	m.mu.Lock()
+
+	// Guard: Cannot adopt while running or stopping
+	if m.status == StatusRunning || m.status == StatusStopping || m.status == StatusFrozen {
+		m.mu.Unlock()
+		tail.close()
+		return fmt.Errorf("process already running or stopping")
+	}
+	// Guard: Nothing left to adopt
+	if !p.Running() {
+		m.mu.Unlock()
+		tail.close()
+		return ErrNotRunning
+	}
+
+	process, err := os.FindProcess(p.PID)
+	if err != nil {
+		m.mu.Unlock()
+		tail.close()
+		return fmt.Errorf("failed to find process %d: %w", p.PID, err)
+	}
+
+	for _, entry := range history {
+		m.seq++
+		entry.Seq = m.seq
+		m.logs.Write(entry)
+	}
+	m.iteration = p.Iteration
+	m.outputOffset = p.Offset
+	m.outputLogged = m.sessionLog.written()
+
+	p.Owner = os.Getpid()
+	if st, err := readProcStat(procRoot, p.Owner); err == nil {
+		p.OwnerTicks = st.Start
+	}
+	if err := m.writePidFile(p); err != nil {
+		m.mu.Unlock()
+		tail.close()
+		return err
+	}
+
+	m.cmd = nil
+	m.adopted = process
+	m.pty = nil
+	m.stdin = nil
+	m.setStatus(StatusRunning)
+	m.doneChan = make(chan struct{})
+	m.exitErr = nil
+	m.startedAt = p.StartedAt
+	m.startSeq = m.seq
+	m.stopCause = StopNone
+	m.lastOutput = time.Now()
+	m.stalled = false
+	doneChan := m.doneChan
+	m.mu.Unlock()
+
+	adopted := fmt.Sprintf("Adopted %s (pid %d, started %s ago)", p.Script, p.PID, p.Started())
+	switch {
+	case tail != nil:
+		m.AppendLog(fmt.Sprintf("%s; following its output in %s", adopted, p.Output))
+	case tailErr != nil:
+		m.AppendLog(fmt.Sprintf("%s; failed to follow its output: %v", adopted, tailErr))
+	default:
+		m.AppendLog(adopted + "; output written since its ralph-tui exited was not captured")
+	}
+
+	go m.monitorUsage(p.PGID, doneChan)
+	go m.watchAdopted(p, tail, doneChan)
+	return nil
+}
+
+// watchAdopted polls an adopted process group until it is gone, capturing
+// what it writes to its output file, then records the exit like the wait
+// goroutine of Start does.
+func (m *Manager) watchAdopted(p PidFile, tail *outputTail, doneChan chan struct{}) {
+	ticker := time.NewTicker(orphanPollInterval)
+	defer ticker.Stop()
+	for p.Running() {
+		<-ticker.C
+		m.captureTail(tail, false)
+	}
+	m.captureTail(tail, true)
+	tail.close()
+
+	m.mu.Lock()
+	m.exitErr = ErrAdoptedExit
+	m.adopted = nil
+	info := newExitInfo(ErrAdoptedExit, m.startedAt, time.Now(), m.stopCause)
+	m.lastExit = &info
+	m.clearPidFile()
+	m.publish(Event{Kind: EventExit, Err: ErrAdoptedExit, Exit: info})
+	m.setStatus(StatusStopped)
+	callback := m.onComplete
+	m.mu.Unlock()
+
+	close(doneChan)
+
+	if callback != nil {
+		callback()
+	}
+}
+
+// captureTail captures the lines added to an adopted process's output file.
+// Stdout and stderr share the file, so every line counts as stdout.
+func (m *Manager) captureTail(tail *outputTail, final bool) {
+	if tail == nil {
+		return
+	}
+	for {
+		line, ok := tail.next(final)
+		if !ok {
+			return
+		}
+		m.capture(StreamStdout, line, time.Now(), tail.offset)
+	}
+}
+
+// trimHistory keeps the first logged lines of a session log, and only the
+// annotations after them: the output there is read again from the output
+// file.
+func trimHistory(history []LogEntry, logged int) []LogEntry {
+	kept := history[:min(logged, len(history))]
+	for _, entry := range history[len(kept):] {
+		if entry.Stream != StreamStdout && entry.Stream != StreamStderr {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// outputTail follows the output file of an adopted process.
+type outputTail struct {
+	file    *os.File
+	reader  *bufio.Reader
+	offset  int64  // End of the last line returned
+	partial string // Start of a line still being written
+}
+
+// openOutputTail opens an output file to follow from offset.
+func openOutputTail(path string, offset int64) (*outputTail, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &outputTail{file: file, reader: bufio.NewReader(file), offset: offset}, nil
+}
+
+// next returns the next line completed since the last call. With final set
+// a last line without a newline is returned too.
+func (t *outputTail) next(final bool) (string, bool) {
+	chunk, err := t.reader.ReadString('\n')
+	t.partial += chunk
+	if err != nil && !(final && t.partial != "") {
+		return "", false
+	}
+	line := t.partial
+	t.offset += int64(len(line))
+	t.partial = ""
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), true
+}
+
+// close closes the file; a nil tail is ignored.
+func (t *outputTail) close() {
+	if t != nil {
+		t.file.Close()
+	}
+}
@@ -0,0 +1,274 @@
+package process
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// startOrphan starts a process group that stands in for a loop left behind
+// by a dead ralph-tui, and returns its pidfile record.
+func startOrphan(t *testing.T, command string, args ...string) PidFile {
+	t.Helper()
+	cmd := exec.Command(command, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start orphan: %v", err)
+	}
+	// Reap it so it does not linger as a zombie once killed
+	go cmd.Wait()
+	t.Cleanup(func() { _ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) })
+
+	p := newPidFile(cmd.Process.Pid, command, time.Now())
+	p.Owner, p.OwnerTicks = 0, 0 // The owner is gone
+	return p
+}
+
+func TestManager_PidFileLifecycle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "loop.pid")
+	mgr := NewManager(DefaultBufferSize)
+	mgr.SetPidFile(path, PidFile{Mode: "build", Runner: "script"})
+	mgr.SetIteration(3)
+
+	if err := mgr.Start("sleep", "0.3"); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+
+	p, err := ReadPidFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read pidfile: %v", err)
+	}
+	pid, _ := mgr.leaderPID()
+	if p.PID != pid || p.PGID != pid || p.Owner != os.Getpid() {
+		t.Errorf("Expected pid/pgid %d owned by %d, got %+v", pid, os.Getpid(), p)
+	}
+	if p.Mode != "build" || p.Runner != "script" || p.Script != "sleep" || p.Iteration != 3 {
+		t.Errorf("Expected descriptive fields recorded, got %+v", p)
+	}
+	if !p.Running() || !p.OwnerRunning() {
+		t.Error("Expected process and owner running")
+	}
+
+	// The iteration is kept current for a later adopter
+	mgr.SetIteration(4)
+	if p, _ := ReadPidFile(path); p.Iteration != 4 {
+		t.Errorf("Expected iteration 4 in pidfile, got %d", p.Iteration)
+	}
+
+	_ = mgr.WaitForExit()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected pidfile removed after exit, got %v", err)
+	}
+	if p.Running() {
+		t.Error("Expected exited process not running")
+	}
+}
+
+func TestManager_PidFileLocksDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "loop.pid")
+	orphan := startOrphan(t, "sleep", "30")
+	if err := orphan.write(path); err != nil {
+		t.Fatalf("Failed to write pidfile: %v", err)
+	}
+
+	mgr := NewManager(DefaultBufferSize)
+	mgr.SetPidFile(path, PidFile{})
+
+	// Guard: A live loop of another ralph-tui holds the directory
+	if err := mgr.Start("sleep", "1"); err == nil {
+		_ = mgr.Stop()
+		t.Fatal("Expected start refused while another loop runs")
+	}
+
+	if err := KillOrphan(orphan, EscalationPolicy{{Signal: syscall.SIGTERM, Wait: 2 * time.Second}}.WithKill()); err != nil {
+		t.Fatalf("Failed to kill orphan: %v", err)
+	}
+	if orphan.Running() {
+		t.Error("Expected orphan gone")
+	}
+
+	// A stale pidfile does not hold the lock
+	if err := mgr.Start("sleep", "0.1"); err != nil {
+		t.Fatalf("Expected start over a stale pidfile, got %v", err)
+	}
+	_ = mgr.WaitForExit()
+}
+
+func TestManager_AdoptOrphan(t *testing.T) {
+	dir := t.TempDir()
+	orphan := startOrphan(t, "sleep", "30")
+	orphan.Iteration = 2
+
+	// The old owner's session log is replayed into the new log buffer
+	log, err := OpenSessionLog(SessionLogConfig{Dir: filepath.Join(dir, "logs")})
+	if err != nil {
+		t.Fatalf("Failed to open session log: %v", err)
+	}
+	log.Write(LogEntry{Time: time.Now(), Stream: StreamStdout, Iteration: 2, Text: "working on it"})
+	log.Close()
+	orphan.LogDir = filepath.Join(dir, "logs")
+	orphan.Session = log.Name()
+
+	path := filepath.Join(dir, "loop.pid")
+	mgr := NewManager(DefaultBufferSize)
+	mgr.SetPidFile(path, PidFile{})
+	if err := mgr.Adopt(orphan); err != nil {
+		t.Fatalf("Failed to adopt: %v", err)
+	}
+
+	logs := mgr.GetLogs()
+	if len(logs) == 0 || logs[0].Text != "working on it" || logs[0].Iteration != 2 {
+		t.Errorf("Expected replayed history first, got %+v", logs)
+	}
+	if !mgr.IsRunning() {
+		t.Fatal("Expected adopted process running")
+	}
+	if p, err := ReadPidFile(path); err != nil || p.Owner != os.Getpid() || p.PID != orphan.PID {
+		t.Errorf("Expected pidfile taken over, got %+v (%v)", p, err)
+	}
+
+	// The adopted group can be frozen and stopped like a started one
+	if err := mgr.Freeze(); err != nil {
+		t.Fatalf("Failed to freeze: %v", err)
+	}
+	if err := mgr.Stop(); err != nil {
+		t.Fatalf("Failed to stop: %v", err)
+	}
+	if mgr.GetStatus() != StatusStopped {
+		t.Errorf("Expected StatusStopped, got %v", mgr.GetStatus())
+	}
+	if exit, ok := mgr.LastExit(); !ok || !errors.Is(exit.Err, ErrAdoptedExit) || !exit.Requested {
+		t.Errorf("Expected requested exit with unknown status, got %+v", exit)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected pidfile removed, got %v", err)
+	}
+
+	// Guard: Nothing left to adopt
+	if err := mgr.Adopt(orphan); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Expected ErrNotRunning, got %v", err)
+	}
+}
+
+func TestManager_RecordsOutputOffset(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "loop.pid")
+	log, err := OpenSessionLog(SessionLogConfig{Dir: filepath.Join(dir, "logs")})
+	if err != nil {
+		t.Fatalf("Failed to open session log: %v", err)
+	}
+	defer log.Close()
+
+	mgr := NewManager(DefaultBufferSize)
+	mgr.SetSessionLog(log)
+	mgr.SetPidFile(path, PidFile{Output: filepath.Join(dir, "output.log")})
+	if err := mgr.Start("sh", "-c", "echo one; echo two; sleep 5"); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	defer mgr.StopImmediate()
+	waitForLog(t, mgr, "two")
+
+	// Any rewrite of the pidfile carries the latest offset
+	mgr.SetIteration(2)
+	p, err := ReadPidFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read pidfile: %v", err)
+	}
+	if p.Offset != int64(len("one\ntwo\n")) || p.Logged != log.written() {
+		t.Errorf("Expected offset 8 at log line %d, got %d at %d", log.written(), p.Offset, p.Logged)
+	}
+}
+
+func TestManager_AdoptFollowsOutput(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output.log")
+	if err := os.WriteFile(output, nil, 0o644); err != nil {
+		t.Fatalf("Failed to create output file: %v", err)
+	}
+
+	// The orphan writes two lines its old owner captured, then more after
+	// that owner is gone, the last without a newline
+	orphan := startOrphan(t, "sh", "-c", `echo one >>"$0"; echo two >>"$0"; sleep 0.5; echo three >>"$0"; printf four >>"$0"`, output)
+	orphan.Output = output
+
+	// The old owner logged both lines, but its pidfile was last written
+	// after the first
+	log, err := OpenSessionLog(SessionLogConfig{Dir: filepath.Join(dir, "logs")})
+	if err != nil {
+		t.Fatalf("Failed to open session log: %v", err)
+	}
+	log.Write(LogEntry{Time: time.Now(), Stream: StreamStdout, Text: "one"})
+	orphan.Offset, orphan.Logged = int64(len("one\n")), log.written()
+	log.Write(LogEntry{Time: time.Now(), Stream: StreamStdout, Text: "two"})
+	log.Write(LogEntry{Time: time.Now(), Stream: StreamSystem, Text: "note"})
+	log.Close()
+	orphan.LogDir = filepath.Join(dir, "logs")
+	orphan.Session = log.Name()
+
+	path := filepath.Join(dir, "loop.pid")
+	mgr := NewManager(DefaultBufferSize)
+	mgr.SetPidFile(path, PidFile{Output: output})
+	var seen []string
+	mgr.OnOutput(func(line string) { seen = append(seen, line) })
+	if err := mgr.Adopt(orphan); err != nil {
+		t.Fatalf("Failed to adopt: %v", err)
+	}
+	if p, err := ReadPidFile(path); err != nil || p.Offset != orphan.Offset {
+		t.Errorf("Expected offset %d kept for the next adopter, got %+v (%v)", orphan.Offset, p, err)
+	}
+
+	_ = mgr.WaitForExit()
+
+	var texts []string
+	for _, entry := range mgr.GetLogs() {
+		if entry.Stream != StreamSystem || entry.Text == "note" {
+			texts = append(texts, entry.Text)
+		}
+	}
+	if got := strings.Join(texts, " "); got != "one note two three four" {
+		t.Errorf("Expected history to the offset then followed output, got %q", got)
+	}
+	if got := strings.Join(seen, " "); got != "two three four" {
+		t.Errorf("Expected followed lines passed to the output callback, got %q", got)
+	}
+	mgr.mu.RLock()
+	offset := mgr.outputOffset
+	mgr.mu.RUnlock()
+	if offset != int64(len("one\ntwo\nthree\nfour")) {
+		t.Errorf("Expected the whole output file captured, got offset %d", offset)
+	}
+}
+
+func TestManager_AdoptWithoutSessionLog(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output.log")
+	if err := os.WriteFile(output, []byte("one\ntwo\n"), 0o644); err != nil {
+		t.Fatalf("Failed to create output file: %v", err)
+	}
+
+	// Without a session log the output before the offset is gone for good
+	orphan := startOrphan(t, "sh", "-c", `sleep 0.3; echo three >>"$0"`, output)
+	orphan.Output = output
+	orphan.Offset = int64(len("one\n"))
+
+	mgr := NewManager(DefaultBufferSize)
+	if err := mgr.Adopt(orphan); err != nil {
+		t.Fatalf("Failed to adopt: %v", err)
+	}
+	_ = mgr.WaitForExit()
+
+	var texts []string
+	for _, entry := range mgr.GetLogs() {
+		if entry.Stream != StreamSystem {
+			texts = append(texts, entry.Text)
+		}
+	}
+	if got := strings.Join(texts, " "); got != "two three" {
+		t.Errorf("Expected output from the offset, got %q", got)
+	}
+}
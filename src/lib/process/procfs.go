@@ -0,0 +1,180 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// procRoot is where the kernel exposes per-process information.
+	procRoot = "/proc"
+
+	// clockTicks is USER_HZ, the unit of CPU times in /proc/<pid>/stat. It is
+	// 100 on every mainstream Linux architecture.
+	clockTicks = 100
+)
+
+// procStat holds the fields of /proc/<pid>/stat the manager cares about.
+type procStat struct {
+	PID     int
+	PPID    int
+	PGID    int
+	Command string
+	State   byte   // R, S, D, T, Z, ...
+	Ticks   uint64 // utime + stime, in clock ticks
+	Start   uint64 // Start time after boot, in clock ticks
+	RSS     uint64 // Resident set size in bytes
+}
+
+// readProcStat parses /proc/<pid>/stat under root.
+func readProcStat(root string, pid int) (procStat, error) {
+	data, err := os.ReadFile(filepath.Join(root, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return procStat{}, err
+	}
+	return parseProcStat(string(data))
+}
+
+// parseProcStat parses the contents of a stat file. The command is wrapped
+// in parentheses and may itself contain spaces and parentheses, so the
+// remaining fields are located from the last ')'.
+func parseProcStat(data string) (procStat, error) {
+	open := strings.IndexByte(data, '(')
+	closing := strings.LastIndexByte(data, ')')
+	if open < 0 || closing < open {
+		return procStat{}, fmt.Errorf("malformed stat line")
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(data[:open]))
+	if err != nil {
+		return procStat{}, fmt.Errorf("malformed pid: %w", err)
+	}
+
+	// fields[0] is field 3 (state) in proc(5) numbering
+	fields := strings.Fields(data[closing+1:])
+	if len(fields) < 22 {
+		return procStat{}, fmt.Errorf("short stat line: %d fields", len(fields))
+	}
+	field := func(n int) string { return fields[n-3] }
+
+	st := procStat{
+		PID:     pid,
+		Command: data[open+1 : closing],
+		State:   field(3)[0],
+	}
+	if st.PPID, err = strconv.Atoi(field(4)); err != nil {
+		return procStat{}, fmt.Errorf("malformed ppid: %w", err)
+	}
+	if st.PGID, err = strconv.Atoi(field(5)); err != nil {
+		return procStat{}, fmt.Errorf("malformed pgrp: %w", err)
+	}
+
+	utime, err := strconv.ParseUint(field(14), 10, 64)
+	if err != nil {
+		return procStat{}, fmt.Errorf("malformed utime: %w", err)
+	}
+	stime, err := strconv.ParseUint(field(15), 10, 64)
+	if err != nil {
+		return procStat{}, fmt.Errorf("malformed stime: %w", err)
+	}
+	st.Ticks = utime + stime
+
+	if st.Start, err = strconv.ParseUint(field(22), 10, 64); err != nil {
+		return procStat{}, fmt.Errorf("malformed starttime: %w", err)
+	}
+
+	pages, err := strconv.ParseInt(field(24), 10, 64)
+	if err != nil {
+		return procStat{}, fmt.Errorf("malformed rss: %w", err)
+	}
+	if pages > 0 {
+		st.RSS = uint64(pages) * uint64(os.Getpagesize())
+	}
+
+	return st, nil
+}
+
+// scanProcs returns every process under root, ordered by PID. Processes
+// that exit while being read are skipped.
+func scanProcs(root string) ([]procStat, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var procs []procStat
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		st, err := readProcStat(root, pid)
+		if err != nil {
+			continue
+		}
+		procs = append(procs, st)
+	}
+
+	sort.Slice(procs, func(i, j int) bool { return procs[i].PID < procs[j].PID })
+	return procs, nil
+}
+
+// listGroup returns every process in the process group pgid, ordered by PID.
+func listGroup(root string, pgid int) ([]procStat, error) {
+	procs, err := scanProcs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var group []procStat
+	for _, st := range procs {
+		if st.PGID == pgid {
+			group = append(group, st)
+		}
+	}
+	return group, nil
+}
+
+// readCmdline returns the argument vector of pid; empty for zombies and
+// kernel threads.
+func readCmdline(root string, pid int) []string {
+	data, err := os.ReadFile(filepath.Join(root, strconv.Itoa(pid), "cmdline"))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimRight(string(data), "\x00"), "\x00")
+}
+
+// readBootTime returns the system boot time from the btime line of
+// /proc/stat.
+func readBootTime(root string) (time.Time, error) {
+	data, err := os.ReadFile(filepath.Join(root, "stat"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, "btime "); ok {
+			secs, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("malformed btime: %w", err)
+			}
+			return time.Unix(secs, 0), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("btime not found")
+}
+
+// countFDs returns the number of open file descriptors of pid, or -1 if
+// they cannot be read.
+func countFDs(root string, pid int) int {
+	entries, err := os.ReadDir(filepath.Join(root, strconv.Itoa(pid), "fd"))
+	if err != nil {
+		return -1
+	}
+	return len(entries)
+}
@@ -0,0 +1,148 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// writeFakeProc creates a /proc/<pid> entry with a stat line and fds.
+func writeFakeProc(t *testing.T, root string, pid, ppid, pgid int, comm string, ticks uint64, rssPages, fds int) {
+	t.Helper()
+
+	dir := filepath.Join(root, strconv.Itoa(pid))
+	if err := os.MkdirAll(filepath.Join(dir, "fd"), 0o755); err != nil {
+		t.Fatalf("Failed to create proc dir: %v", err)
+	}
+
+	// Fields 3-24; utime carries all ticks, stime is 0
+	stat := fmt.Sprintf("%d (%s) S %d %d %d 0 -1 4194304 0 0 0 0 %d 0 0 0 20 0 1 0 100 1000 %d\n",
+		pid, comm, ppid, pgid, pgid, ticks, rssPages)
+	if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0o644); err != nil {
+		t.Fatalf("Failed to write stat: %v", err)
+	}
+	for i := 0; i < fds; i++ {
+		if err := os.WriteFile(filepath.Join(dir, "fd", strconv.Itoa(i)), nil, 0o644); err != nil {
+			t.Fatalf("Failed to write fd: %v", err)
+		}
+	}
+}
+
+func TestParseProcStat_CommandWithParens(t *testing.T) {
+	line := "42 (my (odd) cmd) R 1 42 42 0 -1 0 0 0 0 0 150 50 0 0 20 0 1 0 100 1000 10\n"
+
+	st, err := parseProcStat(line)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if st.PID != 42 || st.PPID != 1 || st.PGID != 42 || st.Command != "my (odd) cmd" || st.State != 'R' {
+		t.Errorf("Unexpected identity fields: %+v", st)
+	}
+	if st.Ticks != 200 {
+		t.Errorf("Expected 200 ticks, got %d", st.Ticks)
+	}
+	if st.RSS != 10*uint64(os.Getpagesize()) {
+		t.Errorf("Expected RSS of 10 pages, got %d", st.RSS)
+	}
+
+	if _, err := parseProcStat("garbage"); err == nil {
+		t.Error("Expected error for malformed line")
+	}
+}
+
+func TestListGroup_FiltersByPGID(t *testing.T) {
+	root := t.TempDir()
+	writeFakeProc(t, root, 100, 1, 100, "opencode", 0, 1, 3)
+	writeFakeProc(t, root, 101, 100, 100, "go test", 0, 1, 2)
+	writeFakeProc(t, root, 200, 1, 200, "unrelated", 0, 1, 1)
+	if err := os.MkdirAll(filepath.Join(root, "self"), 0o755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+
+	group, err := listGroup(root, 100)
+	if err != nil {
+		t.Fatalf("Failed to list group: %v", err)
+	}
+	if len(group) != 2 || group[0].PID != 100 || group[1].PID != 101 {
+		t.Errorf("Expected pids [100 101], got %+v", group)
+	}
+}
+
+func TestUsageSampler_CPUPercent(t *testing.T) {
+	root := t.TempDir()
+	writeFakeProc(t, root, 100, 1, 100, "opencode", 1000, 256, 4)
+	writeFakeProc(t, root, 101, 100, 100, "go", 500, 512, 6)
+
+	sampler := newUsageSampler(root)
+	start := time.Now()
+	first, err := sampler.sample(100, start)
+	if err != nil {
+		t.Fatalf("Failed to sample: %v", err)
+	}
+	if first.CPUPercent != 0 {
+		t.Errorf("Expected 0%% CPU without a previous sample, got %v", first.CPUPercent)
+	}
+
+	// One second later: 50 ticks (50%) for the leader, 100 ticks (100%) for the child
+	writeFakeProc(t, root, 100, 1, 100, "opencode", 1050, 256, 4)
+	writeFakeProc(t, root, 101, 100, 100, "go", 600, 512, 6)
+
+	second, err := sampler.sample(100, start.Add(time.Second))
+	if err != nil {
+		t.Fatalf("Failed to sample: %v", err)
+	}
+	if len(second.Processes) != 2 {
+		t.Fatalf("Expected 2 processes, got %d", len(second.Processes))
+	}
+	if got := second.Processes[0].CPUPercent; got != 50 {
+		t.Errorf("Expected leader at 50%%, got %v", got)
+	}
+	if second.CPUPercent != 150 {
+		t.Errorf("Expected 150%% aggregate, got %v", second.CPUPercent)
+	}
+	if second.FDs != 10 {
+		t.Errorf("Expected 10 fds, got %d", second.FDs)
+	}
+	if second.RSS != 768*uint64(os.Getpagesize()) {
+		t.Errorf("Expected 768 pages of RSS, got %d", second.RSS)
+	}
+}
+
+func TestManager_UsageSampling(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("/proc sampling requires Linux")
+	}
+
+	mgr := NewManager(DefaultBufferSize)
+	mgr.SetUsageInterval(50 * time.Millisecond)
+
+	if _, ok := mgr.Usage(); ok {
+		t.Error("Expected no usage before any run")
+	}
+
+	// A shell with a child in its process group
+	if err := mgr.Start("sh", "-c", "sleep 1 & wait"); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	defer func() { _ = mgr.StopImmediate() }()
+
+	time.Sleep(300 * time.Millisecond)
+
+	usage, ok := mgr.Usage()
+	if !ok {
+		t.Fatal("Expected a usage sample while running")
+	}
+	if len(usage.Processes) < 2 {
+		t.Errorf("Expected the shell and its child, got %+v", usage.Processes)
+	}
+	if usage.RSS == 0 || usage.FDs == 0 {
+		t.Errorf("Expected non-zero RSS and fds, got %+v", usage)
+	}
+	if history := mgr.UsageHistory(); len(history) < 2 {
+		t.Errorf("Expected several samples in history, got %d", len(history))
+	}
+}
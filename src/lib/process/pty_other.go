@@ -0,0 +1,19 @@
+//go:build !linux
+
+package process
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// openPTY is only implemented on Linux.
+func openPTY() (master, slave *os.File, err error) {
+	return nil, nil, fmt.Errorf("pty mode is not supported on %s", runtime.GOOS)
+}
+
+// setWinsize is a no-op where PTYs are unsupported.
+func setWinsize(f *os.File, cols, rows int) error {
+	return nil
+}
@@ -4,10 +4,10 @@ import (
 	"sync"
 )
 
-// RingBuffer is a thread-safe fixed-size circular buffer for log lines.
+// RingBuffer is a thread-safe fixed-size circular buffer for log entries.
 // Once full, new writes overwrite the oldest entries.
 type RingBuffer struct {
-	lines    []string
+	entries  []LogEntry
 	capacity int
 	head     int
 	size     int
@@ -20,47 +20,102 @@ func NewRingBuffer(capacity int) *RingBuffer {
 		capacity = 1000
 	}
 	return &RingBuffer{
-		lines:    make([]string, capacity),
+		entries:  make([]LogEntry, capacity),
 		capacity: capacity,
 	}
 }
 
-// Write appends a line to the buffer.
-func (rb *RingBuffer) Write(line string) {
+// Write appends an entry to the buffer.
+func (rb *RingBuffer) Write(entry LogEntry) {
+	rb.push(entry)
+}
+
+// push appends an entry and returns the one it overwrote, if the buffer was
+// full.
+func (rb *RingBuffer) push(entry LogEntry) (LogEntry, bool) {
 	rb.mu.Lock()
 	defer rb.mu.Unlock()
 
-	rb.lines[rb.head] = line
+	evicted, full := rb.entries[rb.head], rb.size == rb.capacity
+	rb.entries[rb.head] = entry
 	rb.head = (rb.head + 1) % rb.capacity
 
 	if rb.size < rb.capacity {
 		rb.size++
 	}
+	return evicted, full
 }
 
-// ReadAll returns all lines in chronological order (oldest to newest).
-func (rb *RingBuffer) ReadAll() []string {
+// ReadAll returns all entries in chronological order (oldest to newest).
+func (rb *RingBuffer) ReadAll() []LogEntry {
 	rb.mu.RLock()
 	defer rb.mu.RUnlock()
 
 	if rb.size == 0 {
-		return []string{}
+		return []LogEntry{}
 	}
 
-	result := make([]string, rb.size)
+	result := make([]LogEntry, rb.size)
 	if rb.size < rb.capacity {
 		// Buffer not yet full - head is the next write position
-		copy(result, rb.lines[:rb.size])
+		copy(result, rb.entries[:rb.size])
 	} else {
 		// Buffer is full - head points to oldest entry
-		copy(result, rb.lines[rb.head:])
-		copy(result[rb.capacity-rb.head:], rb.lines[:rb.head])
+		copy(result, rb.entries[rb.head:])
+		copy(result[rb.capacity-rb.head:], rb.entries[:rb.head])
+	}
+
+	return result
+}
+
+// ReadLast returns up to the n newest entries in chronological order.
+func (rb *RingBuffer) ReadLast(n int) []LogEntry {
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
+
+	if n > rb.size {
+		n = rb.size
+	}
+	if n <= 0 {
+		return []LogEntry{}
+	}
+
+	result := make([]LogEntry, n)
+	start := (rb.head - n + rb.capacity) % rb.capacity
+	for i := 0; i < n; i++ {
+		result[i] = rb.entries[(start+i)%rb.capacity]
+	}
+
+	return result
+}
+
+// ReadRange returns up to n entries starting at index start, where 0 is the
+// oldest entry still held.
+func (rb *RingBuffer) ReadRange(start, n int) []LogEntry {
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
+
+	if start < 0 {
+		n += start
+		start = 0
+	}
+	if start+n > rb.size {
+		n = rb.size - start
+	}
+	if n <= 0 {
+		return []LogEntry{}
+	}
+
+	result := make([]LogEntry, n)
+	oldest := (rb.head - rb.size + rb.capacity) % rb.capacity
+	for i := 0; i < n; i++ {
+		result[i] = rb.entries[(oldest+start+i)%rb.capacity]
 	}
 
 	return result
 }
 
-// Size returns the current number of lines in the buffer.
+// Size returns the current number of entries in the buffer.
 func (rb *RingBuffer) Size() int {
 	rb.mu.RLock()
 	defer rb.mu.RUnlock()
@@ -73,5 +128,5 @@ func (rb *RingBuffer) Clear() {
 	defer rb.mu.Unlock()
 	rb.head = 0
 	rb.size = 0
-	rb.lines = make([]string, rb.capacity)
+	rb.entries = make([]LogEntry, rb.capacity)
 }
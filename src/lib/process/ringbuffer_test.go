@@ -18,8 +18,8 @@ func TestRingBuffer_Write_ReadAll(t *testing.T) {
 	}
 
 	// Write less than capacity
-	rb.Write("line1")
-	rb.Write("line2")
+	rb.Write(LogEntry{Text: "line1"})
+	rb.Write(LogEntry{Text: "line2"})
 
 	if rb.Size() != 2 {
 		t.Errorf("expected size 2, got %d", rb.Size())
@@ -29,32 +29,32 @@ func TestRingBuffer_Write_ReadAll(t *testing.T) {
 	if len(lines) != 2 {
 		t.Fatalf("expected 2 lines, got %d", len(lines))
 	}
-	if lines[0] != "line1" || lines[1] != "line2" {
+	if lines[0].Text != "line1" || lines[1].Text != "line2" {
 		t.Errorf("unexpected lines: %v", lines)
 	}
 
 	// Write to fill capacity
-	rb.Write("line3")
+	rb.Write(LogEntry{Text: "line3"})
 	lines = rb.ReadAll()
 	if len(lines) != 3 {
 		t.Fatalf("expected 3 lines, got %d", len(lines))
 	}
 
 	// Write beyond capacity - should overwrite oldest
-	rb.Write("line4")
+	rb.Write(LogEntry{Text: "line4"})
 	lines = rb.ReadAll()
 	if len(lines) != 3 {
 		t.Fatalf("expected 3 lines, got %d", len(lines))
 	}
-	if lines[0] != "line2" || lines[1] != "line3" || lines[2] != "line4" {
+	if lines[0].Text != "line2" || lines[1].Text != "line3" || lines[2].Text != "line4" {
 		t.Errorf("expected [line2, line3, line4], got %v", lines)
 	}
 }
 
 func TestRingBuffer_Clear(t *testing.T) {
 	rb := NewRingBuffer(3)
-	rb.Write("line1")
-	rb.Write("line2")
+	rb.Write(LogEntry{Text: "line1"})
+	rb.Write(LogEntry{Text: "line2"})
 
 	rb.Clear()
 
@@ -74,3 +74,24 @@ func TestRingBuffer_ZeroCapacity(t *testing.T) {
 		t.Errorf("expected default capacity 1000, got %d", rb.capacity)
 	}
 }
+
+func TestRingBuffer_ReadLast(t *testing.T) {
+	rb := NewRingBuffer(3)
+	for _, text := range []string{"line1", "line2", "line3", "line4"} {
+		rb.Write(LogEntry{Text: text})
+	}
+
+	lines := rb.ReadLast(2)
+	if len(lines) != 2 || lines[0].Text != "line3" || lines[1].Text != "line4" {
+		t.Errorf("expected [line3, line4], got %v", lines)
+	}
+
+	// Asking for more than is stored returns everything
+	if lines := rb.ReadLast(10); len(lines) != 3 || lines[0].Text != "line2" {
+		t.Errorf("expected [line2, line3, line4], got %v", lines)
+	}
+
+	if lines := rb.ReadLast(0); len(lines) != 0 {
+		t.Errorf("expected no lines, got %d", len(lines))
+	}
+}
@@ -0,0 +1,425 @@
+package process
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultLogDir is where session logs are written, relative to the
+	// loop's working directory.
+	DefaultLogDir = ".ralph/logs"
+
+	// DefaultLogMaxSize rotates a session log once it grows past 10 MiB.
+	DefaultLogMaxSize = 10 << 20
+
+	// DefaultLogMaxAge rotates a session log part after a day.
+	DefaultLogMaxAge = 24 * time.Hour
+
+	// DefaultLogRetain deletes session logs not written to for a week.
+	DefaultLogRetain = 7 * 24 * time.Hour
+
+	// DefaultLogKeep is how many sessions are kept regardless of age.
+	DefaultLogKeep = 20
+
+	// sessionTimeFormat names sessions after their start time.
+	sessionTimeFormat = "20060102-150405"
+
+	// logTimeFormat stamps each line of a session log.
+	logTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+)
+
+// SessionLogConfig configures where session logs go and when they rotate.
+type SessionLogConfig struct {
+	Dir     string        // Directory holding <session>.log files
+	MaxSize int64         // Rotate the current part past this many bytes (0 = never)
+	MaxAge  time.Duration // Rotate the current part after this long (0 = never)
+	Retain  time.Duration // Delete sessions not written to for this long (0 = forever)
+	Keep    int           // Keep at most this many sessions (0 = unlimited)
+	Label   string        // Appended to session names, e.g. the loop name
+}
+
+// labelReplacer keeps a label from reading as a directory or a part number.
+var labelReplacer = strings.NewReplacer("/", "-", ".", "-")
+
+// withDefaults fills in unset fields.
+func (c SessionLogConfig) withDefaults() SessionLogConfig {
+	if c.Dir == "" {
+		c.Dir = DefaultLogDir
+	}
+	return c
+}
+
+// SessionLog appends every captured line to <dir>/<session>.log. Rotated
+// parts are renamed to <session>.1.log, <session>.2.log, ... oldest first.
+type SessionLog struct {
+	cfg      SessionLogConfig
+	name     string
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	parts    int   // Number of rotated parts
+	lines    int   // Lines written across all parts
+	err      error // First write error; later writes are dropped
+	mu       sync.Mutex
+}
+
+// OpenSessionLog starts a new session named after the current time and
+// prunes sessions that fall outside the retention policy.
+func OpenSessionLog(cfg SessionLogConfig) (*SessionLog, error) {
+	cfg = cfg.withDefaults()
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	// Two sessions started within a second get a numeric suffix
+	base := time.Now().Format(sessionTimeFormat)
+	if cfg.Label != "" {
+		base += "-" + labelReplacer.Replace(cfg.Label)
+	}
+	name := base
+	for i := 2; ; i++ {
+		if _, err := os.Stat(filepath.Join(cfg.Dir, name+".log")); os.IsNotExist(err) {
+			break
+		}
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+
+	l := &SessionLog{cfg: cfg, name: name}
+	if err := l.openPart(); err != nil {
+		return nil, err
+	}
+	if err := PruneSessions(cfg, name); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Name returns the session name.
+func (l *SessionLog) Name() string {
+	return l.name
+}
+
+// Path returns the file the session is currently written to.
+func (l *SessionLog) Path() string {
+	return filepath.Join(l.cfg.Dir, l.name+".log")
+}
+
+// Err returns the error that stopped the session log, if any.
+func (l *SessionLog) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// Write appends an entry, rotating first if the current part is full or
+// old. The first failure is kept and disables further writes.
+func (l *SessionLog) Write(entry LogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Guard: Closed or broken
+	if l.file == nil || l.err != nil {
+		return
+	}
+
+	if l.dueForRotation(entry.Time) {
+		if err := l.rotate(); err != nil {
+			l.err = err
+			return
+		}
+	}
+
+	line := formatLogLine(entry)
+	n, err := l.file.WriteString(line)
+	l.size += int64(n)
+	if n == len(line) {
+		l.lines += strings.Count(line, "\n")
+	}
+	if err != nil {
+		l.err = fmt.Errorf("failed to write session log: %w", err)
+	}
+}
+
+// written returns the number of lines written, which is the number of
+// entries ReadSession finds; a nil log has none.
+func (l *SessionLog) written() int {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lines
+}
+
+// Close closes the current part.
+func (l *SessionLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// dueForRotation reports whether the current part is over size or age.
+// Must be called with l.mu held.
+func (l *SessionLog) dueForRotation(now time.Time) bool {
+	if l.size == 0 {
+		return false
+	}
+	if l.cfg.MaxSize > 0 && l.size >= l.cfg.MaxSize {
+		return true
+	}
+	return l.cfg.MaxAge > 0 && now.Sub(l.openedAt) >= l.cfg.MaxAge
+}
+
+// rotate moves the current part aside and starts a new one, then prunes
+// old sessions. Must be called with l.mu held.
+func (l *SessionLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to close session log: %w", err)
+	}
+	l.file = nil
+
+	l.parts++
+	rotated := filepath.Join(l.cfg.Dir, fmt.Sprintf("%s.%d.log", l.name, l.parts))
+	if err := os.Rename(l.Path(), rotated); err != nil {
+		return fmt.Errorf("failed to rotate session log: %w", err)
+	}
+	if err := l.openPart(); err != nil {
+		return err
+	}
+	return PruneSessions(l.cfg, l.name)
+}
+
+// openPart creates the current part. Must be called with l.mu held (or
+// before the log is shared).
+func (l *SessionLog) openPart() error {
+	file, err := os.OpenFile(l.Path(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open session log: %w", err)
+	}
+	l.file = file
+	l.size = 0
+	l.openedAt = time.Now()
+	return nil
+}
+
+// formatLogLine renders an entry as "<time> <stream> <iteration> <text>".
+func formatLogLine(entry LogEntry) string {
+	return fmt.Sprintf("%s %s %d %s\n", entry.Time.Format(logTimeFormat), entry.Stream, entry.Iteration, entry.Text)
+}
+
+// parseLogLine reverses formatLogLine. Lines that do not parse (e.g. written
+// by another tool) are kept as stdout text.
+func parseLogLine(line string) LogEntry {
+	fields := strings.SplitN(line, " ", 4)
+	if len(fields) < 3 {
+		return LogEntry{Text: line}
+	}
+
+	t, err := time.Parse(logTimeFormat, fields[0])
+	if err != nil {
+		return LogEntry{Text: line}
+	}
+	iteration, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return LogEntry{Text: line}
+	}
+
+	entry := LogEntry{Time: t, Iteration: iteration}
+	switch fields[1] {
+	case "stdout":
+		entry.Stream = StreamStdout
+	case "stderr":
+		entry.Stream = StreamStderr
+	case "system":
+		entry.Stream = StreamSystem
+	case "stdin":
+		entry.Stream = StreamStdin
+	default:
+		return LogEntry{Text: line}
+	}
+	if len(fields) == 4 {
+		entry.Text = fields[3]
+	}
+	return entry
+}
+
+// SessionInfo describes a session log on disk.
+type SessionInfo struct {
+	Name      string
+	Files     []string // Parts oldest first; the current part last
+	Size      int64    // Total bytes over all parts
+	StartedAt time.Time
+	UpdatedAt time.Time // Last write to any part
+}
+
+// ListSessions returns the sessions in dir, newest first.
+func ListSessions(dir string) ([]SessionInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	type part struct {
+		index int // 0 for the current part
+		path  string
+	}
+	parts := make(map[string][]part)
+	sessions := make(map[string]*SessionInfo)
+
+	for _, entry := range entries {
+		stem, ok := strings.CutSuffix(entry.Name(), ".log")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		name, index := stem, 0
+		if dot := strings.LastIndexByte(stem, '.'); dot >= 0 {
+			n, err := strconv.Atoi(stem[dot+1:])
+			if err != nil {
+				continue
+			}
+			name, index = stem[:dot], n
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		session, ok := sessions[name]
+		if !ok {
+			session = &SessionInfo{Name: name}
+			if len(name) >= len(sessionTimeFormat) {
+				if t, err := time.ParseInLocation(sessionTimeFormat, name[:len(sessionTimeFormat)], time.Local); err == nil {
+					session.StartedAt = t
+				}
+			}
+			sessions[name] = session
+		}
+		session.Size += info.Size()
+		if info.ModTime().After(session.UpdatedAt) {
+			session.UpdatedAt = info.ModTime()
+		}
+		parts[name] = append(parts[name], part{index, filepath.Join(dir, entry.Name())})
+	}
+
+	result := make([]SessionInfo, 0, len(sessions))
+	for name, session := range sessions {
+		// Rotated parts by number, then the current part
+		p := parts[name]
+		sort.Slice(p, func(i, j int) bool {
+			if (p[i].index == 0) != (p[j].index == 0) {
+				return p[j].index == 0
+			}
+			return p[i].index < p[j].index
+		})
+		for _, part := range p {
+			session.Files = append(session.Files, part.path)
+		}
+		result = append(result, *session)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name > result[j].Name })
+	return result, nil
+}
+
+// ReadSession reads every line of a session, oldest first.
+func ReadSession(info SessionInfo) ([]LogEntry, error) {
+	var entries []LogEntry
+	for _, path := range info.Files {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			entry := parseLogLine(scanner.Text())
+			entry.Seq = uint64(len(entries) + 1)
+			entries = append(entries, entry)
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+	return entries, nil
+}
+
+// FindSession returns the session with the given name, or the newest one
+// for "latest".
+func FindSession(dir, name string) (SessionInfo, error) {
+	sessions, err := ListSessions(dir)
+	if err != nil {
+		return SessionInfo{}, err
+	}
+	if name == "latest" && len(sessions) > 0 {
+		return sessions[0], nil
+	}
+	for _, session := range sessions {
+		if session.Name == name {
+			return session, nil
+		}
+	}
+	return SessionInfo{}, fmt.Errorf("no session %q in %s", name, dir)
+}
+
+// PruneSessions deletes sessions that have not been written to within
+// cfg.Retain and the oldest ones beyond cfg.Keep. The active session is
+// never deleted.
+func PruneSessions(cfg SessionLogConfig, active string) error {
+	cfg = cfg.withDefaults()
+	sessions, err := ListSessions(cfg.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	kept := 0
+	for _, session := range sessions {
+		expired := cfg.Retain > 0 && time.Since(session.UpdatedAt) > cfg.Retain
+		surplus := cfg.Keep > 0 && kept >= cfg.Keep
+		if session.Name == active || (!expired && !surplus) {
+			kept++
+			continue
+		}
+		for _, path := range session.Files {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to delete old session log: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// SetSessionLog mirrors every subsequently captured line into log (nil
+// stops mirroring). The caller keeps ownership and closes it.
+func (m *Manager) SetSessionLog(log *SessionLog) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessionLog = log
+}
+
+// SessionLog returns the session log lines are mirrored into, if any.
+func (m *Manager) SessionLog() *SessionLog {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.sessionLog
+}
@@ -0,0 +1,177 @@
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogLine_RoundTrip(t *testing.T) {
+	entry := LogEntry{
+		Time:      time.Date(2026, 10, 16, 15, 4, 5, 123000000, time.UTC),
+		Stream:    StreamStderr,
+		Iteration: 7,
+		Text:      "error: something  went wrong",
+	}
+
+	line := strings.TrimSuffix(formatLogLine(entry), "\n")
+	got := parseLogLine(line)
+	if !got.Time.Equal(entry.Time) || got.Stream != entry.Stream || got.Iteration != entry.Iteration || got.Text != entry.Text {
+		t.Errorf("Expected %+v, got %+v", entry, got)
+	}
+
+	if got := parseLogLine("not a log line"); got.Text != "not a log line" || got.Stream != StreamStdout {
+		t.Errorf("Expected foreign line kept as stdout text, got %+v", got)
+	}
+}
+
+func TestSessionLog_MirrorsManagerOutput(t *testing.T) {
+	dir := t.TempDir()
+	log, err := OpenSessionLog(SessionLogConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("Failed to open session log: %v", err)
+	}
+	defer log.Close()
+
+	mgr := NewManager(DefaultBufferSize)
+	mgr.SetSessionLog(log)
+	mgr.SetIteration(2)
+	mgr.AppendLog("starting")
+	if err := mgr.Start("sh", "-c", "echo out; echo err >&2"); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	_ = mgr.WaitForExit()
+
+	session, err := FindSession(dir, "latest")
+	if err != nil {
+		t.Fatalf("Failed to find session: %v", err)
+	}
+	if session.Name != log.Name() {
+		t.Errorf("Expected latest session %s, got %s", log.Name(), session.Name)
+	}
+
+	entries, err := ReadSession(session)
+	if err != nil {
+		t.Fatalf("Failed to read session: %v", err)
+	}
+	found := make(map[string]LogEntry)
+	for _, entry := range entries {
+		found[entry.Text] = entry
+	}
+	if entry, ok := found["starting"]; !ok || entry.Stream != StreamSystem || entry.Iteration != 2 {
+		t.Errorf("Expected system line for iteration 2, got %+v", entry)
+	}
+	if entry, ok := found["out"]; !ok || entry.Stream != StreamStdout {
+		t.Errorf("Expected stdout line, got %+v", entry)
+	}
+	if entry, ok := found["err"]; !ok || entry.Stream != StreamStderr {
+		t.Errorf("Expected stderr line, got %+v", entry)
+	}
+}
+
+func TestSessionLog_RotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	log, err := OpenSessionLog(SessionLogConfig{Dir: dir, MaxSize: 100})
+	if err != nil {
+		t.Fatalf("Failed to open session log: %v", err)
+	}
+	defer log.Close()
+
+	for i := 0; i < 10; i++ {
+		log.Write(LogEntry{Time: time.Now(), Text: strings.Repeat("x", 40)})
+	}
+	if err := log.Err(); err != nil {
+		t.Fatalf("Unexpected write error: %v", err)
+	}
+
+	sessions, err := ListSessions(dir)
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("Expected one session, got %v (%v)", sessions, err)
+	}
+	session := sessions[0]
+	if len(session.Files) < 3 {
+		t.Fatalf("Expected several parts, got %v", session.Files)
+	}
+	if last := session.Files[len(session.Files)-1]; last != log.Path() {
+		t.Errorf("Expected current part last, got %s", last)
+	}
+	if first := filepath.Base(session.Files[0]); first != log.Name()+".1.log" {
+		t.Errorf("Expected oldest part first, got %s", first)
+	}
+
+	entries, err := ReadSession(session)
+	if err != nil {
+		t.Fatalf("Failed to read session: %v", err)
+	}
+	if len(entries) != 10 {
+		t.Errorf("Expected 10 lines across parts, got %d", len(entries))
+	}
+}
+
+func TestSessionLog_DottedLabel(t *testing.T) {
+	dir := t.TempDir()
+	log, err := OpenSessionLog(SessionLogConfig{Dir: dir, MaxSize: 100, Label: "api.v2/fix"})
+	if err != nil {
+		t.Fatalf("Failed to open session log: %v", err)
+	}
+	defer log.Close()
+
+	for i := 0; i < 5; i++ {
+		log.Write(LogEntry{Time: time.Now(), Text: strings.Repeat("x", 40)})
+	}
+	if err := log.Err(); err != nil {
+		t.Fatalf("Unexpected write error: %v", err)
+	}
+	if !strings.HasSuffix(log.Name(), "-api-v2-fix") {
+		t.Errorf("Expected label without dots or slashes, got %s", log.Name())
+	}
+
+	// Every part, rotated or current, belongs to the one session
+	sessions, err := ListSessions(dir)
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("Expected one session, got %v (%v)", sessions, err)
+	}
+	if sessions[0].Name != log.Name() || len(sessions[0].Files) < 2 {
+		t.Errorf("Expected session %s with several parts, got %+v", log.Name(), sessions[0])
+	}
+	if found, err := FindSession(dir, log.Name()); err != nil || found.Name != log.Name() {
+		t.Errorf("Expected session found by name, got %+v (%v)", found, err)
+	}
+}
+
+func TestPruneSessions(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-48 * time.Hour)
+	for _, name := range []string{"20260101-000000.log", "20260101-000000.1.log", "20260102-000000.log", "20260103-000000.log", "20260104-000000.log"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("line\n"), 0o644); err != nil {
+			t.Fatalf("Failed to write log: %v", err)
+		}
+	}
+	// The oldest session has not been written to for two days
+	for _, name := range []string{"20260101-000000.log", "20260101-000000.1.log"} {
+		if err := os.Chtimes(filepath.Join(dir, name), old, old); err != nil {
+			t.Fatalf("Failed to age log: %v", err)
+		}
+	}
+
+	if err := PruneSessions(SessionLogConfig{Dir: dir, Retain: 24 * time.Hour, Keep: 2}, "20260102-000000"); err != nil {
+		t.Fatalf("Failed to prune: %v", err)
+	}
+
+	sessions, err := ListSessions(dir)
+	if err != nil {
+		t.Fatalf("Failed to list sessions: %v", err)
+	}
+	var names []string
+	for _, session := range sessions {
+		names = append(names, session.Name)
+	}
+	// Newest two are kept, plus the active session
+	want := "20260104-000000,20260103-000000,20260102-000000"
+	if got := strings.Join(names, ","); got != want {
+		t.Errorf("Expected sessions %s, got %s", want, got)
+	}
+}
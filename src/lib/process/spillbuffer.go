@@ -0,0 +1,231 @@
+package process
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// spillIndexStride is how many spilled entries share one index offset.
+// Reads seek to the nearest indexed entry and skip forward, so the index
+// costs 8 bytes per stride instead of per line.
+const spillIndexStride = 256
+
+// LogStore holds captured log entries in chronological order. Indexes run
+// from 0 (the oldest entry still held) to Size()-1.
+type LogStore interface {
+	Write(entry LogEntry)
+	ReadAll() []LogEntry
+	ReadLast(n int) []LogEntry
+	ReadRange(start, n int) []LogEntry
+	Size() int
+	Clear()
+}
+
+var (
+	_ LogStore = (*RingBuffer)(nil)
+	_ LogStore = (*SpillBuffer)(nil)
+)
+
+// SpillBuffer keeps the newest entries in a RingBuffer and pages older ones
+// out to a segment file, so the whole session stays readable while memory
+// stays bounded.
+type SpillBuffer struct {
+	mem     *RingBuffer
+	file    *os.File
+	writer  *bufio.Writer
+	offset  int64      // Bytes written to the segment
+	index   []int64    // Offset of every spillIndexStride-th spilled entry
+	spilled int        // Entries on disk
+	err     error      // First segment error; older entries are dropped after it
+	mu      sync.Mutex // Reads take it too: they flush the shared writer
+}
+
+// NewSpillBuffer creates a buffer holding capacity entries in memory and
+// the rest in a segment file under dir (the system temp dir if empty). The
+// segment is deleted by Close.
+func NewSpillBuffer(capacity int, dir string) (*SpillBuffer, error) {
+	file, err := os.CreateTemp(dir, "ralph-scrollback-*.seg")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scrollback segment: %w", err)
+	}
+
+	return &SpillBuffer{
+		mem:    NewRingBuffer(capacity),
+		file:   file,
+		writer: bufio.NewWriter(file),
+	}, nil
+}
+
+// Write appends an entry, spilling the oldest in-memory entry to disk once
+// memory is full.
+func (sb *SpillBuffer) Write(entry LogEntry) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	evicted, full := sb.mem.push(entry)
+	if !full || sb.err != nil || sb.file == nil {
+		return
+	}
+
+	data, err := json.Marshal(evicted)
+	if err != nil {
+		sb.err = fmt.Errorf("failed to encode log entry: %w", err)
+		return
+	}
+	data = append(data, '\n')
+	if _, err := sb.writer.Write(data); err != nil {
+		sb.err = fmt.Errorf("failed to write scrollback segment: %w", err)
+		return
+	}
+
+	if sb.spilled%spillIndexStride == 0 {
+		sb.index = append(sb.index, sb.offset)
+	}
+	sb.offset += int64(len(data))
+	sb.spilled++
+}
+
+// ReadAll returns every entry, including those paged out to disk.
+func (sb *SpillBuffer) ReadAll() []LogEntry {
+	return sb.ReadRange(0, sb.Size())
+}
+
+// ReadLast returns up to the n newest entries in chronological order.
+func (sb *SpillBuffer) ReadLast(n int) []LogEntry {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	// Guard: The newest entries are usually all in memory
+	if n <= sb.mem.Size() {
+		return sb.mem.ReadLast(n)
+	}
+	total := sb.spilled + sb.mem.Size()
+	return sb.readRangeLocked(total-n, n)
+}
+
+// ReadRange returns up to n entries starting at index start.
+func (sb *SpillBuffer) ReadRange(start, n int) []LogEntry {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.readRangeLocked(start, n)
+}
+
+// readRangeLocked reads the disk part of a range from the segment and the
+// rest from memory. Must be called with sb.mu held.
+func (sb *SpillBuffer) readRangeLocked(start, n int) []LogEntry {
+	if start < 0 {
+		n += start
+		start = 0
+	}
+	if total := sb.spilled + sb.mem.Size(); start+n > total {
+		n = total - start
+	}
+	if n <= 0 {
+		return []LogEntry{}
+	}
+
+	result := make([]LogEntry, 0, n)
+	if start < sb.spilled {
+		count := min(n, sb.spilled-start)
+		entries, err := sb.readSegment(start, count)
+		if err != nil {
+			// Keep the shape of the range so callers' indexes stay valid
+			entries = make([]LogEntry, count)
+			for i := range entries {
+				entries[i] = LogEntry{Stream: StreamSystem, Text: fmt.Sprintf("[scrollback unavailable: %v]", err)}
+			}
+		}
+		result = append(result, entries...)
+		start, n = sb.spilled, n-count
+	}
+	return append(result, sb.mem.ReadRange(start-sb.spilled, n)...)
+}
+
+// readSegment decodes count spilled entries starting at index start. Must
+// be called with sb.mu held.
+func (sb *SpillBuffer) readSegment(start, count int) ([]LogEntry, error) {
+	// Make buffered writes visible to ReadAt
+	if err := sb.writer.Flush(); err != nil {
+		return nil, err
+	}
+
+	block := start / spillIndexStride
+	reader := bufio.NewReader(io.NewSectionReader(sb.file, sb.index[block], sb.offset-sb.index[block]))
+
+	entries := make([]LogEntry, 0, count)
+	for i := block * spillIndexStride; len(entries) < count; i++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return nil, fmt.Errorf("failed to read scrollback segment: %w", err)
+		}
+		if i < start {
+			continue
+		}
+		var entry LogEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("corrupt scrollback segment: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Size returns the number of entries held in memory and on disk.
+func (sb *SpillBuffer) Size() int {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.spilled + sb.mem.Size()
+}
+
+// Clear empties memory and truncates the segment.
+func (sb *SpillBuffer) Clear() {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	sb.mem.Clear()
+	sb.offset = 0
+	sb.index = nil
+	sb.spilled = 0
+	sb.err = nil
+	if sb.file != nil {
+		sb.writer.Reset(sb.file)
+		if err := sb.file.Truncate(0); err != nil {
+			sb.err = fmt.Errorf("failed to truncate scrollback segment: %w", err)
+		} else if _, err := sb.file.Seek(0, io.SeekStart); err != nil {
+			sb.err = fmt.Errorf("failed to rewind scrollback segment: %w", err)
+		}
+	}
+}
+
+// Err returns the error that stopped spilling, if any.
+func (sb *SpillBuffer) Err() error {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.err
+}
+
+// Close deletes the segment; the buffer keeps only its in-memory entries.
+func (sb *SpillBuffer) Close() error {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	// Guard: Already closed
+	if sb.file == nil {
+		return nil
+	}
+
+	name := sb.file.Name()
+	err := sb.file.Close()
+	sb.file = nil
+	sb.offset = 0
+	sb.index = nil
+	sb.spilled = 0
+	if removeErr := os.Remove(name); err == nil {
+		err = removeErr
+	}
+	return err
+}
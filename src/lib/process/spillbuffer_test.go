@@ -0,0 +1,127 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func newTestSpillBuffer(t *testing.T, capacity int) *SpillBuffer {
+	t.Helper()
+
+	sb, err := NewSpillBuffer(capacity, t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create spill buffer: %v", err)
+	}
+	t.Cleanup(func() { sb.Close() })
+	return sb
+}
+
+func TestRingBuffer_ReadRange(t *testing.T) {
+	rb := NewRingBuffer(3)
+	for i := 1; i <= 5; i++ {
+		rb.Write(LogEntry{Text: fmt.Sprintf("line%d", i)})
+	}
+
+	// Holds line3..line5
+	got := rb.ReadRange(1, 5)
+	if len(got) != 2 || got[0].Text != "line4" || got[1].Text != "line5" {
+		t.Errorf("Unexpected range: %v", got)
+	}
+	if got := rb.ReadRange(-1, 2); len(got) != 1 || got[0].Text != "line3" {
+		t.Errorf("Expected negative start clipped, got %v", got)
+	}
+}
+
+func TestSpillBuffer_KeepsEverything(t *testing.T) {
+	sb := newTestSpillBuffer(t, 10)
+
+	const total = 1000
+	for i := 0; i < total; i++ {
+		sb.Write(LogEntry{Seq: uint64(i + 1), Stream: Stream(i % 2), Text: fmt.Sprintf("line %d", i)})
+	}
+
+	if sb.Size() != total {
+		t.Fatalf("Expected %d entries, got %d", total, sb.Size())
+	}
+
+	all := sb.ReadAll()
+	if len(all) != total {
+		t.Fatalf("Expected ReadAll to return %d entries, got %d", total, len(all))
+	}
+	for i, entry := range all {
+		if entry.Seq != uint64(i+1) || entry.Text != fmt.Sprintf("line %d", i) || entry.Stream != Stream(i%2) {
+			t.Fatalf("Entry %d out of order or corrupt: %+v", i, entry)
+		}
+	}
+
+	// A range straddling the segment and memory
+	got := sb.ReadRange(985, 10)
+	if len(got) != 10 || got[0].Text != "line 985" || got[9].Text != "line 994" {
+		t.Errorf("Unexpected straddling range: first %q last %q", got[0].Text, got[len(got)-1].Text)
+	}
+
+	// A range in the middle of an index block
+	got = sb.ReadRange(300, 3)
+	if len(got) != 3 || got[0].Text != "line 300" || got[2].Text != "line 302" {
+		t.Errorf("Unexpected range: %v", got)
+	}
+
+	last := sb.ReadLast(15)
+	if len(last) != 15 || last[0].Text != "line 985" || last[14].Text != "line 999" {
+		t.Errorf("Unexpected tail: %v", last)
+	}
+}
+
+func TestSpillBuffer_ClearAndClose(t *testing.T) {
+	sb := newTestSpillBuffer(t, 2)
+	for i := 0; i < 10; i++ {
+		sb.Write(LogEntry{Text: fmt.Sprintf("old %d", i)})
+	}
+
+	sb.Clear()
+	if sb.Size() != 0 {
+		t.Fatalf("Expected empty buffer after Clear, got %d", sb.Size())
+	}
+
+	for i := 0; i < 5; i++ {
+		sb.Write(LogEntry{Text: fmt.Sprintf("new %d", i)})
+	}
+	all := sb.ReadAll()
+	if len(all) != 5 || all[0].Text != "new 0" || all[4].Text != "new 4" {
+		t.Errorf("Unexpected entries after Clear: %v", all)
+	}
+
+	name := sb.file.Name()
+	if err := sb.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("Expected segment deleted, got %v", err)
+	}
+	if sb.Size() != 2 {
+		t.Errorf("Expected in-memory entries kept after Close, got %d", sb.Size())
+	}
+}
+
+func TestManager_SpillBufferStderrTail(t *testing.T) {
+	mgr := NewManager(DefaultBufferSize)
+	mgr.SetLogStore(newTestSpillBuffer(t, 5))
+
+	// The failing run prints far more than fits in memory
+	if err := mgr.Start("sh", "-c", "echo boom >&2; for i in $(seq 1 50); do echo $i; done; exit 1"); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	_ = mgr.WaitForExit()
+
+	exit, ok := mgr.LastExit()
+	if !ok {
+		t.Fatal("Expected exit info")
+	}
+	if len(exit.Stderr) != 1 || exit.Stderr[0] != "boom" {
+		t.Errorf("Expected stderr tail from spilled scrollback, got %v", exit.Stderr)
+	}
+	if mgr.LogCount() < 51 {
+		t.Errorf("Expected every line kept, got %d", mgr.LogCount())
+	}
+}
@@ -0,0 +1,91 @@
+package process
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxStallInterval bounds how late a stall is noticed.
+const maxStallInterval = time.Second
+
+// SetStallTimeout sets the quiet period after which a running process that
+// has produced no output is reported as stalled (0 disables detection).
+func (m *Manager) SetStallTimeout(timeout time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stallTimeout = timeout
+}
+
+// OnStall registers a callback invoked once per stall, with the quiet time.
+func (m *Manager) OnStall(fn func(quiet time.Duration)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onStall = fn
+}
+
+// Stalled reports whether the running process is stalled and for how long
+// it has been quiet.
+func (m *Manager) Stalled() (time.Duration, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if !m.stalled {
+		return 0, false
+	}
+	return time.Since(m.lastOutput), true
+}
+
+// LastOutput returns when the process last wrote a line (or started).
+func (m *Manager) LastOutput() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastOutput
+}
+
+// markOutput records process activity and clears a stall. Must be called
+// with m.mu held.
+func (m *Manager) markOutput(now time.Time) {
+	m.lastOutput = now
+	if m.stalled {
+		m.stalled = false
+		m.publish(Event{Kind: EventStallCleared, Message: "output resumed"})
+	}
+}
+
+// monitorStall watches the current process for quiet periods until done is
+// closed. A frozen process is silent by request and never counts as stalled.
+func (m *Manager) monitorStall(done <-chan struct{}) {
+	m.mu.RLock()
+	timeout := m.stallTimeout
+	m.mu.RUnlock()
+
+	// Guard: Detection disabled for this run
+	if timeout <= 0 {
+		return
+	}
+
+	interval := min(timeout/10, maxStallInterval)
+	ticker := time.NewTicker(max(interval, time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		m.mu.Lock()
+		quiet := time.Since(m.lastOutput)
+		fire := !m.stalled && m.status == StatusRunning && quiet >= timeout
+		if fire {
+			m.stalled = true
+			m.publish(Event{Kind: EventStall, Message: fmt.Sprintf("no output for %s", quiet.Truncate(time.Second))})
+		}
+		callback := m.onStall
+		m.mu.Unlock()
+
+		if fire && callback != nil {
+			callback(quiet)
+		}
+	}
+}
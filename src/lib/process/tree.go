@@ -0,0 +1,150 @@
+package process
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+)
+
+// ProcessNode is one process of the loop's tree, listed depth-first.
+type ProcessNode struct {
+	PID       int
+	PPID      int
+	Depth     int // 0 for the group leader (and orphans adopted from the group)
+	Command   string
+	Args      []string // Full command line; empty for zombies
+	State     byte     // R running, S sleeping, D disk wait, T stopped, Z zombie, ...
+	StartedAt time.Time
+}
+
+// Runtime returns how long the process has been running.
+func (n ProcessNode) Runtime() time.Duration {
+	if n.StartedAt.IsZero() {
+		return 0
+	}
+	return time.Since(n.StartedAt)
+}
+
+// processTree builds the tree under leader from a /proc scan: every process
+// in the leader's group plus all of their descendants, even those that moved
+// to a group of their own.
+func processTree(root string, leader int) ([]ProcessNode, error) {
+	procs, err := scanProcs(root)
+	if err != nil {
+		return nil, err
+	}
+	bootTime, _ := readBootTime(root)
+
+	byPID := make(map[int]procStat, len(procs))
+	children := make(map[int][]int)
+	for _, st := range procs {
+		byPID[st.PID] = st
+		children[st.PPID] = append(children[st.PPID], st.PID)
+	}
+
+	// Tree roots: the leader, then group members whose parent is outside the
+	// tree (e.g. re-parented to init after their parent exited)
+	inTree := make(map[int]bool)
+	var mark func(pid int)
+	mark = func(pid int) {
+		inTree[pid] = true
+		for _, child := range children[pid] {
+			mark(child)
+		}
+	}
+	var roots []int
+	if _, ok := byPID[leader]; ok {
+		roots = append(roots, leader)
+		mark(leader)
+	}
+	for _, st := range procs {
+		if st.PGID == leader && !inTree[st.PID] {
+			roots = append(roots, st.PID)
+			mark(st.PID)
+		}
+	}
+
+	var nodes []ProcessNode
+	var walk func(pid, depth int)
+	walk = func(pid, depth int) {
+		st := byPID[pid]
+		node := ProcessNode{
+			PID:     st.PID,
+			PPID:    st.PPID,
+			Depth:   depth,
+			Command: st.Command,
+			Args:    readCmdline(root, pid),
+			State:   st.State,
+		}
+		if !bootTime.IsZero() {
+			node.StartedAt = bootTime.Add(time.Duration(st.Start) * time.Second / clockTicks)
+		}
+		nodes = append(nodes, node)
+
+		for _, child := range children[pid] {
+			walk(child, depth+1)
+		}
+	}
+	for _, pid := range roots {
+		walk(pid, 0)
+	}
+
+	return nodes, nil
+}
+
+// ProcessTree returns the processes spawned by the running command,
+// depth-first starting at the group leader.
+func (m *Manager) ProcessTree() ([]ProcessNode, error) {
+	leader, err := m.leaderPID()
+	if err != nil {
+		return nil, err
+	}
+	return processTree(procRoot, leader)
+}
+
+// SignalProcess sends sig to a single process of the running command's tree,
+// leaving the rest of the loop alone (e.g. to kill a runaway test binary).
+func (m *Manager) SignalProcess(pid int, sig syscall.Signal) error {
+	leader, err := m.leaderPID()
+	if err != nil {
+		return err
+	}
+
+	// Guard: Only processes the loop spawned may be signalled
+	nodes, err := processTree(procRoot, leader)
+	if err != nil {
+		return fmt.Errorf("failed to read process tree: %w", err)
+	}
+	found := false
+	for _, node := range nodes {
+		if node.PID == pid {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("process %d is not part of the loop", pid)
+	}
+
+	if err := syscall.Kill(pid, sig); err != nil {
+		return fmt.Errorf("failed to send %s to %d: %w", SignalName(sig), pid, err)
+	}
+	m.AppendLog(fmt.Sprintf("Sent %s to process %d", SignalName(sig), pid))
+	return nil
+}
+
+// leaderPID returns the PID (and process group) of the running command.
+func (m *Manager) leaderPID() (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// Guard: The tree only exists while the command runs
+	if m.status != StatusRunning && m.status != StatusStopping && m.status != StatusFrozen {
+		return 0, ErrNotRunning
+	}
+	process := m.process()
+	if process == nil {
+		return 0, ErrNotRunning
+	}
+	return process.Pid, nil
+}
@@ -0,0 +1,111 @@
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestProcessTree_WalksGroupAndDescendants(t *testing.T) {
+	root := t.TempDir()
+	writeFakeProc(t, root, 100, 1, 100, "bash", 0, 1, 0)
+	writeFakeProc(t, root, 101, 100, 100, "opencode", 0, 1, 0)
+	writeFakeProc(t, root, 102, 101, 102, "go", 0, 1, 0) // Moved to its own group
+	writeFakeProc(t, root, 103, 1, 100, "orphan", 0, 1, 0)
+	writeFakeProc(t, root, 200, 1, 200, "unrelated", 0, 1, 0)
+
+	cmdline := "go\x00test\x00./...\x00"
+	if err := os.WriteFile(filepath.Join(root, "102", "cmdline"), []byte(cmdline), 0o644); err != nil {
+		t.Fatalf("Failed to write cmdline: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "stat"), []byte("cpu 0 0 0\nbtime 1700000000\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write stat: %v", err)
+	}
+
+	nodes, err := processTree(root, 100)
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+
+	var got []string
+	for _, node := range nodes {
+		got = append(got, strconv.Itoa(node.PID)+":"+strconv.Itoa(node.Depth))
+	}
+	if strings.Join(got, " ") != "100:0 101:1 102:2 103:0" {
+		t.Errorf("Unexpected tree order: %v", got)
+	}
+
+	if args := strings.Join(nodes[2].Args, " "); args != "go test ./..." {
+		t.Errorf("Expected cmdline 'go test ./...', got %q", args)
+	}
+	// starttime is 100 ticks (1s) after boot in writeFakeProc
+	if want := time.Unix(1700000001, 0); !nodes[0].StartedAt.Equal(want) {
+		t.Errorf("Expected start %v, got %v", want, nodes[0].StartedAt)
+	}
+}
+
+func TestManager_SignalProcess(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("process tree requires Linux")
+	}
+
+	mgr := NewManager(DefaultBufferSize)
+
+	if _, err := mgr.ProcessTree(); err == nil {
+		t.Error("Expected error without a running process")
+	}
+
+	if err := mgr.Start("sh", "-c", "sleep 30 & wait; echo child gone; sleep 30"); err != nil {
+		t.Fatalf("Failed to start process: %v", err)
+	}
+	defer func() { _ = mgr.StopImmediate() }()
+
+	// Wait for the child to appear
+	var child int
+	deadline := time.Now().Add(2 * time.Second)
+	for child == 0 && time.Now().Before(deadline) {
+		nodes, err := mgr.ProcessTree()
+		if err != nil {
+			t.Fatalf("Failed to read tree: %v", err)
+		}
+		for _, node := range nodes {
+			if node.Depth == 1 && node.Command == "sleep" {
+				child = node.PID
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if child == 0 {
+		t.Fatal("Expected a sleep child in the tree")
+	}
+
+	// Signals outside the tree are refused
+	if err := mgr.SignalProcess(os.Getpid(), syscall.SIGTERM); err == nil {
+		t.Error("Expected error signalling a process outside the loop")
+	}
+
+	if err := mgr.SignalProcess(child, syscall.SIGKILL); err != nil {
+		t.Fatalf("Failed to signal child: %v", err)
+	}
+
+	// Only the child died; the loop keeps running
+	gone := false
+	deadline = time.Now().Add(2 * time.Second)
+	for !gone && time.Now().Before(deadline) {
+		for _, entry := range mgr.GetLogs() {
+			gone = gone || entry.Text == "child gone"
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if !gone {
+		t.Error("Expected the shell to notice its child exit")
+	}
+	if !mgr.IsRunning() {
+		t.Error("Expected the loop to keep running after killing a child")
+	}
+}
@@ -0,0 +1,147 @@
+package process
+
+import (
+	"time"
+)
+
+const (
+	// DefaultUsageInterval is how often the process group is sampled.
+	DefaultUsageInterval = 2 * time.Second
+
+	// DefaultUsageHistory is how many samples the manager keeps.
+	DefaultUsageHistory = 60
+)
+
+// ProcessUsage is the resource usage of one process in the group.
+type ProcessUsage struct {
+	PID        int
+	PPID       int
+	Command    string
+	CPUPercent float64 // Share of one CPU since the previous sample
+	RSS        uint64  // Resident memory in bytes
+	FDs        int     // Open file descriptors (-1 if unreadable)
+}
+
+// UsageSample is one reading of the whole process group.
+type UsageSample struct {
+	Time       time.Time
+	Processes  []ProcessUsage // Ordered by PID
+	CPUPercent float64        // Sum over the group
+	RSS        uint64
+	FDs        int
+}
+
+// usageSampler turns consecutive /proc readings into CPU percentages.
+type usageSampler struct {
+	root      string
+	prevTicks map[int]uint64
+	prevTime  time.Time
+}
+
+// newUsageSampler creates a sampler reading from the given proc root.
+func newUsageSampler(root string) *usageSampler {
+	return &usageSampler{root: root, prevTicks: make(map[int]uint64)}
+}
+
+// sample reads the process group pgid. CPU usage is measured against the
+// previous sample; processes seen for the first time report 0%.
+func (s *usageSampler) sample(pgid int, now time.Time) (UsageSample, error) {
+	group, err := listGroup(s.root, pgid)
+	if err != nil {
+		return UsageSample{}, err
+	}
+
+	elapsed := now.Sub(s.prevTime).Seconds()
+	ticks := make(map[int]uint64, len(group))
+	sample := UsageSample{Time: now}
+
+	for _, st := range group {
+		usage := ProcessUsage{
+			PID:     st.PID,
+			PPID:    st.PPID,
+			Command: st.Command,
+			RSS:     st.RSS,
+			FDs:     countFDs(s.root, st.PID),
+		}
+
+		if prev, ok := s.prevTicks[st.PID]; ok && elapsed > 0 && st.Ticks >= prev {
+			usage.CPUPercent = float64(st.Ticks-prev) / clockTicks / elapsed * 100
+		}
+		ticks[st.PID] = st.Ticks
+
+		sample.Processes = append(sample.Processes, usage)
+		sample.CPUPercent += usage.CPUPercent
+		sample.RSS += usage.RSS
+		if usage.FDs > 0 {
+			sample.FDs += usage.FDs
+		}
+	}
+
+	s.prevTicks = ticks
+	s.prevTime = now
+	return sample, nil
+}
+
+// SetUsageInterval sets how often subsequent runs are sampled (0 disables
+// sampling).
+func (m *Manager) SetUsageInterval(interval time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usageInterval = interval
+}
+
+// Usage returns the latest resource sample; false if none was taken yet.
+func (m *Manager) Usage() (UsageSample, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.usage) == 0 {
+		return UsageSample{}, false
+	}
+	return m.usage[len(m.usage)-1], true
+}
+
+// UsageHistory returns up to DefaultUsageHistory recent samples, oldest
+// first. History carries over from one run to the next.
+func (m *Manager) UsageHistory() []UsageSample {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := make([]UsageSample, len(m.usage))
+	copy(history, m.usage)
+	return history
+}
+
+// monitorUsage samples the process group led by pgid until done is closed.
+func (m *Manager) monitorUsage(pgid int, done <-chan struct{}) {
+	m.mu.RLock()
+	interval := m.usageInterval
+	m.mu.RUnlock()
+
+	// Guard: Sampling disabled
+	if interval <= 0 {
+		return
+	}
+
+	sampler := newUsageSampler(procRoot)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Take the first reading right away so CPU% is available after one tick
+	for {
+		sample, err := sampler.sample(pgid, time.Now())
+		if err == nil && len(sample.Processes) > 0 {
+			m.mu.Lock()
+			m.usage = append(m.usage, sample)
+			if len(m.usage) > DefaultUsageHistory {
+				m.usage = m.usage[len(m.usage)-DefaultUsageHistory:]
+			}
+			m.mu.Unlock()
+		}
+
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -2,8 +2,10 @@ package state
 
 import (
 	"sync"
+	"time"
 
 	"github.com/alex/ralph-tui/src/lib/process"
+	"github.com/alex/ralph-tui/src/lib/worktree"
 )
 
 // Mode represents the loop execution mode.
@@ -15,14 +17,77 @@ const (
 	ModePlanWork Mode = "plan-work"
 )
 
+// Runner selects what drives loop iterations.
+type Runner string
+
+const (
+	RunnerNative Runner = "native" // Go loop engine invokes the agent directly
+	RunnerScript Runner = "script" // Legacy loop.sh owns the iteration loop
+)
+
+// RestartMode selects when a loop that ended unexpectedly is restarted.
+type RestartMode string
+
+const (
+	RestartNever     RestartMode = "never"      // Leave a crashed loop stopped
+	RestartOnFailure RestartMode = "on-failure" // Restart after a crash or failed iteration
+	RestartAlways    RestartMode = "always"     // Also restart a runner that exited cleanly but early
+)
+
+// RestartPolicy configures automatic restarts of a crashed loop.
+type RestartPolicy struct {
+	Mode        RestartMode
+	MaxRestarts int           // 0 = unlimited
+	Backoff     time.Duration // Delay before the first restart, doubled for each consecutive one
+	MaxBackoff  time.Duration // Upper bound for the delay
+}
+
+// TimeoutAction selects what happens after an iteration hits its time limit.
+type TimeoutAction string
+
+const (
+	TimeoutContinue TimeoutAction = "continue" // Kill the iteration and start the next one
+	TimeoutStop     TimeoutAction = "stop"     // Kill the iteration and stop the loop
+)
+
+// TimeoutPolicy configures the per-iteration watchdog.
+type TimeoutPolicy struct {
+	Limit  time.Duration // Wall-clock limit per iteration; 0 disables the watchdog
+	Action TimeoutAction
+}
+
+// StallAction selects what happens when the agent goes quiet.
+type StallAction string
+
+const (
+	StallWarn      StallAction = "warn"      // Only show a warning
+	StallInterrupt StallAction = "interrupt" // Interrupt the iteration and carry on with the next one
+	StallStop      StallAction = "stop"      // Stop the loop
+)
+
+// StallPolicy configures stall detection.
+type StallPolicy struct {
+	Quiet  time.Duration // Quiet period before a stall is raised; 0 disables detection
+	Action StallAction
+}
+
 // State represents the centralized application state.
 type State struct {
 	// Process state
+	Name          string // Loop name, unique within the TUI
 	ProcessStatus process.Status
 	Mode          Mode
 	MaxIterations int
 	WorkDesc      string // For plan-work mode
 	ScriptPath    string // Path to loop.sh script
+	Runner        Runner
+	Restart       RestartPolicy
+	Timeout       TimeoutPolicy
+	Stall         StallPolicy
+	StartOptions  process.StartOptions     // Environment, working directory and extra arguments
+	LogDir        string                   // Directory of per-session log files ("" = not persisted)
+	Worktree      *worktree.Worktree       // Git worktree the loop runs in, if any
+	Escalation    process.EscalationConfig // Signal ladders for stop, interrupt and timeouts
 
 	// Runtime state
 	CurrentIteration int
@@ -37,13 +102,21 @@ type State struct {
 	mu sync.RWMutex
 }
 
+// DefaultLoopName names the loop configured by the top-level flags.
+const DefaultLoopName = "main"
+
 // NewState creates a new application state.
 func NewState() *State {
 	return &State{
+		Name:          DefaultLoopName,
 		ProcessStatus: process.StatusIdle,
 		Mode:          ModeBuild,
 		MaxIterations: 0,
 		ScriptPath:    "./loop.sh",
+		Runner:        RunnerNative,
+		Restart:       RestartPolicy{Mode: RestartNever},
+		Timeout:       TimeoutPolicy{Action: TimeoutContinue},
+		Stall:         StallPolicy{Action: StallWarn},
 		CurrentView:   "dashboard",
 	}
 }
@@ -118,6 +191,123 @@ func (s *State) GetScriptPath() string {
 	return s.ScriptPath
 }
 
+// SetRunner updates the loop runner.
+func (s *State) SetRunner(runner Runner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Runner = runner
+}
+
+// GetRunner returns the loop runner.
+func (s *State) GetRunner() Runner {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Runner
+}
+
+// SetRestartPolicy updates the restart policy for subsequent loop starts.
+func (s *State) SetRestartPolicy(policy RestartPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Restart = policy
+}
+
+// GetRestartPolicy returns the restart policy.
+func (s *State) GetRestartPolicy() RestartPolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Restart
+}
+
+// SetTimeoutPolicy updates the iteration watchdog for subsequent loop starts.
+func (s *State) SetTimeoutPolicy(policy TimeoutPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Timeout = policy
+}
+
+// GetTimeoutPolicy returns the iteration watchdog configuration.
+func (s *State) GetTimeoutPolicy() TimeoutPolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Timeout
+}
+
+// SetStallPolicy updates stall detection for subsequent loop starts.
+func (s *State) SetStallPolicy(policy StallPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Stall = policy
+}
+
+// GetStallPolicy returns the stall detection configuration.
+func (s *State) GetStallPolicy() StallPolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Stall
+}
+
+// SetStartOptions updates how subsequent loop processes are started.
+func (s *State) SetStartOptions(opts process.StartOptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.StartOptions = opts
+}
+
+// SetLogDir records where session logs are written.
+func (s *State) SetLogDir(dir string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.LogDir = dir
+}
+
+// GetLogDir returns the session log directory, empty if logs are not
+// persisted.
+func (s *State) GetLogDir() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.LogDir
+}
+
+// GetStartOptions returns the environment, working directory and extra
+// arguments for loop processes.
+func (s *State) GetStartOptions() process.StartOptions {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.StartOptions
+}
+
+// SetEscalation updates the signal ladders used to take the loop down.
+func (s *State) SetEscalation(cfg process.EscalationConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Escalation = cfg
+}
+
+// GetEscalation returns the signal ladders, defaults filled in.
+func (s *State) GetEscalation() process.EscalationConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Escalation.WithDefaults()
+}
+
+// SetWorktree records the git worktree the loop runs in (nil for none).
+func (s *State) SetWorktree(wt *worktree.Worktree) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Worktree = wt
+}
+
+// GetWorktree returns the git worktree the loop runs in, if any.
+func (s *State) GetWorktree() (worktree.Worktree, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.Worktree == nil {
+		return worktree.Worktree{}, false
+	}
+	return *s.Worktree, true
+}
+
 // IncrementIteration increments the current iteration count.
 func (s *State) IncrementIteration() {
 	s.mu.Lock()
@@ -132,6 +322,13 @@ func (s *State) GetCurrentIteration() int {
 	return s.CurrentIteration
 }
 
+// SetCurrentIteration updates the current iteration count.
+func (s *State) SetCurrentIteration(iteration int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.CurrentIteration = iteration
+}
+
 // ResetIteration resets the iteration counter.
 func (s *State) ResetIteration() {
 	s.mu.Lock()
@@ -139,6 +336,20 @@ func (s *State) ResetIteration() {
 	s.CurrentIteration = 0
 }
 
+// SetName renames the loop.
+func (s *State) SetName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Name = name
+}
+
+// GetName returns the loop name.
+func (s *State) GetName() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Name
+}
+
 // SetGitBranch updates the git branch.
 func (s *State) SetGitBranch(branch string) {
 	s.mu.Lock()
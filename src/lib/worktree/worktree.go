@@ -0,0 +1,351 @@
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultDir holds the worktrees, relative to the main checkout.
+	DefaultDir = ".ralph/worktrees"
+
+	// BranchPrefix starts the branch of every worktree.
+	BranchPrefix = "ralph/"
+
+	// ControlDir holds ralph's logs, pidfile and sentinels in every
+	// checkout; it is never part of the work.
+	ControlDir = ".ralph"
+)
+
+var (
+	// ErrDirty is returned when uncommitted changes would be lost or mixed
+	// into a merge.
+	ErrDirty = errors.New("uncommitted changes")
+
+	// ErrConflict is returned when a merge-back conflicts; the merge is
+	// aborted and the main checkout left as it was.
+	ErrConflict = errors.New("merge conflict")
+
+	// slugRegex matches runs of characters not allowed in a slug.
+	slugRegex = regexp.MustCompile(`[^a-z0-9._-]+`)
+)
+
+// Worktree is a git worktree a loop runs in.
+type Worktree struct {
+	Slug   string
+	Path   string
+	Branch string // BranchPrefix + Slug
+	Base   string // Branch it was created from and is merged back into
+	Root   string // Main checkout it belongs to
+}
+
+// Repo creates and manages the worktrees of one main checkout.
+type Repo struct {
+	Root string // Top level of the main checkout
+	Dir  string // Where worktrees are created
+}
+
+// Open finds the main checkout containing dir (the current directory if
+// empty).
+func Open(dir string) (*Repo, error) {
+	root, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("not a git repository: %w", err)
+	}
+	return &Repo{Root: root, Dir: filepath.Join(root, DefaultDir)}, nil
+}
+
+// Slugify turns a loop name into a slug usable in a branch and directory
+// name.
+func Slugify(name string) string {
+	slug := slugRegex.ReplaceAllString(strings.ToLower(name), "-")
+	slug = strings.Trim(slug, "-.")
+	if slug == "" {
+		return "loop"
+	}
+	return slug
+}
+
+// Create adds a worktree for slug on branch ralph/<slug>, branching from
+// the main checkout's current branch. An existing worktree for slug is
+// reused, and so is an existing branch; either keeps the base it was
+// first created from.
+func (r *Repo) Create(slug string) (Worktree, error) {
+	base, err := r.CurrentBranch()
+	if err != nil {
+		return Worktree{}, err
+	}
+	wt := Worktree{
+		Slug:   slug,
+		Path:   filepath.Join(r.Dir, slug),
+		Branch: BranchPrefix + slug,
+		Base:   base,
+		Root:   r.Root,
+	}
+	if err := r.exclude(); err != nil {
+		return Worktree{}, err
+	}
+
+	// Guard: Already checked out from an earlier session
+	existing, err := r.List()
+	if err != nil {
+		return Worktree{}, err
+	}
+	for _, other := range existing {
+		if other.Path == wt.Path {
+			wt.Branch = other.Branch
+			if stored := r.storedBase(wt.Branch); stored != "" {
+				wt.Base = stored
+			}
+			return wt, nil
+		}
+	}
+
+	if _, err := git(r.Root, "rev-parse", "--verify", "--quiet", "refs/heads/"+wt.Branch); err == nil {
+		_, err = git(r.Root, "worktree", "add", wt.Path, wt.Branch)
+		if err != nil {
+			return Worktree{}, fmt.Errorf("failed to add worktree: %w", err)
+		}
+		if stored := r.storedBase(wt.Branch); stored != "" {
+			wt.Base = stored
+		}
+		return wt, nil
+	}
+	if _, err := git(r.Root, "worktree", "add", "-b", wt.Branch, wt.Path, base); err != nil {
+		return Worktree{}, fmt.Errorf("failed to add worktree: %w", err)
+	}
+	if _, err := git(r.Root, "config", baseKey(wt.Branch), base); err != nil {
+		return Worktree{}, fmt.Errorf("failed to record base of %s: %w", wt.Branch, err)
+	}
+	return wt, nil
+}
+
+// storedBase returns the base recorded when branch was created, or "" for
+// a branch created some other way.
+func (r *Repo) storedBase(branch string) string {
+	base, err := git(r.Root, "config", "--get", baseKey(branch))
+	if err != nil {
+		return ""
+	}
+	return base
+}
+
+// baseKey is the git config key holding the base of a worktree branch.
+func baseKey(branch string) string {
+	return "branch." + branch + ".ralphBase"
+}
+
+// List returns the worktrees under r.Dir.
+func (r *Repo) List() ([]Worktree, error) {
+	output, err := git(r.Root, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worktrees: %w", err)
+	}
+
+	// Compare resolved paths: the temp dir may be behind a symlink
+	dir := resolve(r.Dir)
+
+	var result []Worktree
+	for _, record := range strings.Split(output, "\n\n") {
+		var wt Worktree
+		for _, line := range strings.Split(record, "\n") {
+			if path, ok := strings.CutPrefix(line, "worktree "); ok {
+				wt.Path = path
+			} else if ref, ok := strings.CutPrefix(line, "branch "); ok {
+				wt.Branch = strings.TrimPrefix(ref, "refs/heads/")
+			}
+		}
+		if wt.Path == "" || filepath.Dir(resolve(wt.Path)) != dir {
+			continue
+		}
+		wt.Slug = filepath.Base(wt.Path)
+		wt.Root = r.Root
+		wt.Path = filepath.Join(r.Dir, wt.Slug)
+		result = append(result, wt)
+	}
+	return result, nil
+}
+
+// CurrentBranch returns the branch checked out in the main checkout.
+func (r *Repo) CurrentBranch() (string, error) {
+	branch, err := git(r.Root, "branch", "--show-current")
+	if err != nil {
+		return "", fmt.Errorf("failed to determine branch: %w", err)
+	}
+	// Guard: Worktrees need a branch to start from and merge back into
+	if branch == "" {
+		return "", errors.New("main checkout has a detached HEAD")
+	}
+	return branch, nil
+}
+
+// Ahead returns how many commits the worktree's branch has that its base
+// does not.
+func (r *Repo) Ahead(wt Worktree) (int, error) {
+	output, err := git(r.Root, "rev-list", "--count", wt.Base+".."+wt.Branch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compare %s with %s: %w", wt.Branch, wt.Base, err)
+	}
+	return strconv.Atoi(output)
+}
+
+// Merge merges the worktree's branch into its base in the main checkout.
+// The main checkout must have the base checked out and no uncommitted
+// changes. A conflicting merge is aborted and returns ErrConflict.
+func (r *Repo) Merge(wt Worktree) error {
+	current, err := r.CurrentBranch()
+	if err != nil {
+		return err
+	}
+	// Guard: Merge into the branch the worktree came from
+	if current != wt.Base {
+		return fmt.Errorf("main checkout is on %s, not %s", current, wt.Base)
+	}
+	if dirty, err := isDirty(r.Root); err != nil {
+		return err
+	} else if dirty {
+		return fmt.Errorf("main checkout has %w", ErrDirty)
+	}
+
+	message := fmt.Sprintf("Merge %s into %s", wt.Branch, wt.Base)
+	if _, err := git(r.Root, "merge", "--no-ff", "--no-edit", "-m", message, wt.Branch); err != nil {
+		if _, abortErr := git(r.Root, "merge", "--abort"); abortErr != nil {
+			return fmt.Errorf("failed to merge %s: %w", wt.Branch, err)
+		}
+		return fmt.Errorf("%s into %s: %w", wt.Branch, wt.Base, ErrConflict)
+	}
+	return nil
+}
+
+// Push pushes a branch of the main checkout to origin.
+func (r *Repo) Push(branch string) error {
+	if _, err := git(r.Root, "push", "origin", branch); err != nil {
+		return fmt.Errorf("failed to push %s: %w", branch, err)
+	}
+	return nil
+}
+
+// Remove deletes the worktree and its branch. Without force it refuses to
+// drop uncommitted changes or commits not merged into the base.
+func (r *Repo) Remove(wt Worktree, force bool) error {
+	if !force {
+		if dirty, err := isDirty(wt.Path); err != nil {
+			return err
+		} else if dirty {
+			return fmt.Errorf("worktree %s has %w", wt.Slug, ErrDirty)
+		}
+		if ahead, err := r.Ahead(wt); err != nil {
+			return err
+		} else if ahead > 0 {
+			return fmt.Errorf("%s has %d commit(s) not merged into %s", wt.Branch, ahead, wt.Base)
+		}
+	}
+
+	args := []string{"worktree", "remove", wt.Path}
+	if force {
+		args = []string{"worktree", "remove", "--force", wt.Path}
+	}
+	if _, err := git(r.Root, args...); err != nil {
+		return fmt.Errorf("failed to remove worktree: %w", err)
+	}
+	if _, err := git(r.Root, "branch", "-D", wt.Branch); err != nil {
+		return fmt.Errorf("failed to delete branch: %w", err)
+	}
+	return nil
+}
+
+// exclude keeps ralph's control directory and the worktrees out of the
+// status of every checkout, so neither counts as uncommitted changes.
+func (r *Repo) exclude() error {
+	commonDir, err := git(r.Root, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return fmt.Errorf("failed to locate git directory: %w", err)
+	}
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(r.Root, commonDir)
+	}
+	path := filepath.Join(commonDir, "info", "exclude")
+
+	patterns := []string{"/" + ControlDir + "/"}
+	rel, err := filepath.Rel(r.Root, r.Dir)
+	// Worktrees outside the checkout or under the control directory need no
+	// pattern of their own
+	if err == nil && !strings.HasPrefix(rel, "..") && !strings.HasPrefix(filepath.ToSlash(rel)+"/", patterns[0][1:]) {
+		patterns = append(patterns, "/"+filepath.ToSlash(rel)+"/")
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	present := make(map[string]bool)
+	for _, line := range strings.Split(string(content), "\n") {
+		present[strings.TrimSpace(line)] = true
+	}
+	var missing string
+	for _, pattern := range patterns {
+		if !present[pattern] {
+			missing += pattern + "\n"
+		}
+	}
+	if missing == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+	if len(content) > 0 && !strings.HasSuffix(string(content), "\n") {
+		missing = "\n" + missing
+	}
+	if _, err := file.WriteString(missing); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// isDirty reports whether a checkout has uncommitted changes.
+func isDirty(dir string) (bool, error) {
+	output, err := git(dir, "status", "--porcelain")
+	if err != nil {
+		return false, fmt.Errorf("failed to check status of %s: %w", dir, err)
+	}
+	return output != "", nil
+}
+
+// resolve returns path with symlinks evaluated, or path itself if that
+// fails.
+func resolve(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+// git runs a git command in dir and returns its trimmed output. Errors carry
+// git's own message.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,247 @@
+package worktree
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestRepo creates a main checkout on branch main with one commit and a
+// bare remote as origin.
+func newTestRepo(t *testing.T) (*Repo, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	base := t.TempDir()
+	remote := filepath.Join(base, "remote.git")
+	root := filepath.Join(base, "repo")
+	run(t, base, "init", "--bare", "-b", "main", remote)
+	run(t, base, "init", "-b", "main", root)
+	commitFile(t, root, "README", "hello\n")
+	run(t, root, "remote", "add", "origin", remote)
+	run(t, root, "push", "-u", "origin", "main")
+
+	repo, err := Open(root)
+	if err != nil {
+		t.Fatalf("Failed to open repo: %v", err)
+	}
+	return repo, remote
+}
+
+// run runs git in dir and fails the test on error.
+func run(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	output, err := git(dir, args...)
+	if err != nil {
+		t.Fatalf("git %s: %v", strings.Join(args, " "), err)
+	}
+	return output
+}
+
+// commitFile writes a file and commits it.
+func commitFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	run(t, dir, "add", name)
+	run(t, dir, "commit", "-m", "Update "+name)
+}
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"main":          "main",
+		"Fix Login Bug": "fix-login-bug",
+		"api/v2":        "api-v2",
+		"--":            "loop",
+		"":              "loop",
+	}
+	for name, want := range tests {
+		if got := Slugify(name); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestRepo_CreateAndList(t *testing.T) {
+	repo, _ := newTestRepo(t)
+
+	wt, err := repo.Create("auth")
+	if err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	if wt.Branch != "ralph/auth" || wt.Base != "main" {
+		t.Errorf("Expected ralph/auth from main, got %s from %s", wt.Branch, wt.Base)
+	}
+	if got := run(t, wt.Path, "branch", "--show-current"); got != "ralph/auth" {
+		t.Errorf("Expected worktree on ralph/auth, got %s", got)
+	}
+
+	// The worktrees do not show up as untracked files in the main checkout
+	if status := run(t, repo.Root, "status", "--porcelain"); status != "" {
+		t.Errorf("Expected clean main checkout, got %q", status)
+	}
+
+	// Creating again reuses the existing worktree
+	again, err := repo.Create("auth")
+	if err != nil {
+		t.Fatalf("Failed to reuse worktree: %v", err)
+	}
+	if again.Path != wt.Path {
+		t.Errorf("Expected %s, got %s", wt.Path, again.Path)
+	}
+
+	worktrees, err := repo.List()
+	if err != nil {
+		t.Fatalf("Failed to list worktrees: %v", err)
+	}
+	if len(worktrees) != 1 || worktrees[0].Slug != "auth" || worktrees[0].Branch != "ralph/auth" {
+		t.Errorf("Expected only the auth worktree, got %+v", worktrees)
+	}
+}
+
+func TestRepo_MergeAndPush(t *testing.T) {
+	repo, remote := newTestRepo(t)
+	wt, err := repo.Create("feature")
+	if err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	commitFile(t, wt.Path, "feature.txt", "done\n")
+
+	if ahead, err := repo.Ahead(wt); err != nil || ahead != 1 {
+		t.Errorf("Expected 1 commit ahead, got %d (%v)", ahead, err)
+	}
+
+	// Removing unmerged work needs force
+	if err := repo.Remove(wt, false); err == nil {
+		t.Error("Expected remove to refuse unmerged commits")
+	}
+
+	if err := repo.Merge(wt); err != nil {
+		t.Fatalf("Failed to merge: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo.Root, "feature.txt")); err != nil {
+		t.Errorf("Expected merged file in main checkout: %v", err)
+	}
+	if err := repo.Push("main"); err != nil {
+		t.Fatalf("Failed to push: %v", err)
+	}
+	if got := run(t, remote, "log", "-1", "--format=%s", "main"); got != "Merge ralph/feature into main" {
+		t.Errorf("Expected merge commit on remote, got %q", got)
+	}
+
+	// Merged work is removed without force, branch included
+	if err := repo.Remove(wt, false); err != nil {
+		t.Fatalf("Failed to remove worktree: %v", err)
+	}
+	if _, err := os.Stat(wt.Path); !os.IsNotExist(err) {
+		t.Errorf("Expected worktree directory gone, got %v", err)
+	}
+	if _, err := git(repo.Root, "rev-parse", "--verify", "refs/heads/ralph/feature"); err == nil {
+		t.Error("Expected branch deleted")
+	}
+}
+
+func TestRepo_MergeConflictIsAborted(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	wt, err := repo.Create("conflict")
+	if err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	commitFile(t, wt.Path, "README", "from worktree\n")
+	commitFile(t, repo.Root, "README", "from main\n")
+
+	err = repo.Merge(wt)
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got %v", err)
+	}
+	if status := run(t, repo.Root, "status", "--porcelain"); status != "" {
+		t.Errorf("Expected merge aborted and checkout clean, got %q", status)
+	}
+}
+
+func TestRepo_MergeRequiresCleanBase(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	wt, err := repo.Create("dirty")
+	if err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	commitFile(t, wt.Path, "work.txt", "work\n")
+
+	if err := os.WriteFile(filepath.Join(repo.Root, "README"), []byte("edited\n"), 0o644); err != nil {
+		t.Fatalf("Failed to edit README: %v", err)
+	}
+	if err := repo.Merge(wt); !errors.Is(err, ErrDirty) {
+		t.Errorf("Expected ErrDirty, got %v", err)
+	}
+
+	// Force drops the worktree and its unmerged branch
+	if err := repo.Remove(wt, true); err != nil {
+		t.Fatalf("Failed to force remove: %v", err)
+	}
+}
+
+func TestRepo_ControlDirIsNotDirty(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	wt, err := repo.Create("logs")
+	if err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+	commitFile(t, wt.Path, "work.txt", "work\n")
+
+	// Logs, pidfiles and sentinels land in .ralph of both checkouts, and the
+	// repo has no .gitignore entry for them
+	for _, dir := range []string{repo.Root, wt.Path} {
+		if err := os.MkdirAll(filepath.Join(dir, ".ralph", "logs"), 0o755); err != nil {
+			t.Fatalf("Failed to create control directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, ".ralph", "logs", "session.log"), []byte("line\n"), 0o644); err != nil {
+			t.Fatalf("Failed to write log: %v", err)
+		}
+	}
+
+	if err := repo.Merge(wt); err != nil {
+		t.Fatalf("Expected merge with a clean main checkout, got %v", err)
+	}
+	if err := repo.Remove(wt, false); err != nil {
+		t.Errorf("Expected remove without force, got %v", err)
+	}
+}
+
+func TestRepo_ReuseKeepsBase(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	wt, err := repo.Create("reuse")
+	if err != nil {
+		t.Fatalf("Failed to create worktree: %v", err)
+	}
+
+	// The main checkout moves on to another branch between sessions
+	run(t, repo.Root, "checkout", "-b", "other")
+
+	again, err := repo.Create("reuse")
+	if err != nil {
+		t.Fatalf("Failed to reuse worktree: %v", err)
+	}
+	if again.Base != "main" {
+		t.Errorf("Expected reused worktree based on main, got %s", again.Base)
+	}
+
+	// So does a branch whose worktree was removed
+	run(t, repo.Root, "worktree", "remove", wt.Path)
+	again, err = repo.Create("reuse")
+	if err != nil {
+		t.Fatalf("Failed to recreate worktree: %v", err)
+	}
+	if again.Base != "main" {
+		t.Errorf("Expected recreated worktree based on main, got %s", again.Base)
+	}
+}
@@ -0,0 +1,151 @@
+package tui
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/alex/ralph-tui/src/lib/attach"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Serve runs the model as a daemon: the loops keep running in this process
+// and each client attached through ln gets a fresh TUI on its terminal.
+// A new attach takes over from the current one. Returns once the user
+// chooses to stop the loops, or on SIGTERM/SIGINT after stopping them.
+func Serve(m *Model, ln *attach.Listener) error {
+	// The daemon has no terminal; render for the one attached
+	lipgloss.SetColorProfile(termenv.ANSI)
+	m.detachable = true
+	// Nobody reads events until the first client attaches
+	m.detach()
+
+	signal.Ignore(syscall.SIGHUP)
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(stop)
+
+	sessions := make(chan *attach.Session)
+	go func() {
+		defer close(sessions)
+		for {
+			session, err := ln.AcceptSession()
+			if err != nil {
+				return
+			}
+			sessions <- session
+		}
+	}()
+
+	var next *attach.Session
+	for {
+		if next == nil {
+			select {
+			case session, ok := <-sessions:
+				if !ok {
+					m.stopLoops()
+					return errors.New("attach socket closed")
+				}
+				next = session
+			case <-stop:
+				m.stopLoops()
+				return nil
+			}
+		}
+		session := next
+		next = nil
+		m.reattach()
+
+		program := tea.NewProgram(m,
+			tea.WithInput(session),
+			tea.WithOutput(session),
+			tea.WithAltScreen(),
+			tea.WithoutSignalHandler(),
+		)
+		go func() {
+			for size := range session.Resizes() {
+				program.Send(tea.WindowSizeMsg{Width: size.Cols, Height: size.Rows})
+			}
+		}()
+		done := make(chan struct{})
+		go func() {
+			_, _ = program.Run()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			// Detached, or the loops were stopped
+		case <-session.Done():
+			// The client went away without detaching
+			program.Quit()
+			<-done
+		case other, ok := <-sessions:
+			// Another attach takes over; a closed socket ends the daemon
+			program.Quit()
+			<-done
+			if !ok {
+				m.shutdown = true
+				m.stopLoops()
+			}
+			next = other
+		case <-stop:
+			// The program must be done with the model before it is touched
+			program.Quit()
+			<-done
+			m.shutdown = true
+			m.stopLoops()
+		}
+		m.detach()
+
+		if m.shutdown {
+			// Remove the socket before hanging up so the client sees the
+			// daemon is gone
+			ln.Close()
+			session.Close()
+			return nil
+		}
+		session.Close()
+	}
+}
+
+// reattach prepares the model for a new client: prompts left open by the
+// previous one are dismissed and the loops' events are subscribed to again.
+func (m *Model) reattach() {
+	m.showQuitConfirm = false
+	m.inputActive = false
+	m.procsPending = nil
+	m.worktreePending = nil
+	m.ready = false
+
+	for _, inst := range m.loops {
+		manager := inst.engine.Manager()
+		if inst.events != nil {
+			manager.Unsubscribe(inst.events)
+		}
+		inst.events = manager.Subscribe()
+		syncLoopState(inst)
+	}
+}
+
+// detach drops the loops' event subscriptions once a client is gone.
+// Nobody reads them until the next attach, and their queues are unbounded;
+// a wait left behind by the finished program sees its channel close.
+func (m *Model) detach() {
+	for _, inst := range m.loops {
+		inst.engine.Manager().Unsubscribe(inst.events)
+		inst.events = nil
+	}
+}
+
+// stopLoops stops every loop that owns a run.
+func (m *Model) stopLoops() {
+	for _, inst := range m.loops {
+		if inst.active() {
+			_ = inst.engine.Stop()
+		}
+	}
+}
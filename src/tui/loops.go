@@ -0,0 +1,168 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alex/ralph-tui/src/lib/loop"
+	"github.com/alex/ralph-tui/src/lib/process"
+	"github.com/alex/ralph-tui/src/lib/state"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// loopInstance is one named loop with its own state, engine and manager.
+type loopInstance struct {
+	state  *state.State
+	engine *loop.Engine
+	events *process.Subscription
+	prompt string // Last output line if it asks for input
+
+	stopping bool // A stop is waiting for the process to go down
+
+	inputQueue []pendingInput // Input waiting behind the write in flight
+	inputBusy  bool           // A write to the loop's stdin is in flight
+
+	worktreeBusy    bool   // A merge-back or cleanup is in progress
+	worktreeRemoved bool   // The worktree is gone; the loop cannot start again
+	worktreeNote    string // Outcome of the last worktree action
+	worktreeFailed  bool   // worktreeNote is an error
+}
+
+// pendingInput is text for the loop's stdin, or the end of input.
+type pendingInput struct {
+	text string
+	eof  bool
+}
+
+// active returns true while the loop owns a run (running, paused or frozen).
+func (inst *loopInstance) active() bool {
+	return inst.engine.IsRunning() || inst.engine.IsPaused() || inst.engine.IsFrozen()
+}
+
+// AddLoop adds another loop to the TUI. Its name comes from the state and
+// should be unique.
+func (m *Model) AddLoop(st *state.State, eng *loop.Engine) {
+	m.loops = append(m.loops, &loopInstance{
+		state:  st,
+		engine: eng,
+		events: eng.Manager().Subscribe(),
+	})
+}
+
+// loop returns the loop currently shown.
+func (m *Model) loop() *loopInstance {
+	return m.loops[m.active]
+}
+
+// selectLoop shows the i-th loop. The current tab is kept; view positions
+// that belong to the previous loop's output are reset.
+func (m *Model) selectLoop(i int) {
+	view := ""
+	if m.state != nil {
+		view = m.state.GetCurrentView()
+	}
+
+	m.active = i
+	inst := m.loops[i]
+	m.state = inst.state
+	m.engine = inst.engine
+	m.manager = inst.engine.Manager()
+
+	if view != "" {
+		m.state.SetCurrentView(view)
+	}
+	m.logsEnd = 0
+	m.sessionView = nil
+	m.sessionsOpen = false
+	m.inputActive = false
+	m.procsPending = nil
+	m.worktreePending = nil
+	m.procsSelected = 0
+	m.planCache = nil
+	m.specsCache = make(map[string]*fileCache)
+	m.specsListCache = nil
+	m.specsViewingFile = false
+	if view == "procs" {
+		m.refreshProcs()
+	}
+}
+
+// anyLoopActive returns true while any loop owns a run.
+func (m *Model) anyLoopActive() bool {
+	for _, inst := range m.loops {
+		if inst.active() {
+			return true
+		}
+	}
+	return false
+}
+
+// renderLoopsOverview renders a table of every loop's status, the shown one
+// highlighted.
+func (m *Model) renderLoopsOverview() []string {
+	header := fmt.Sprintf("  %-12s %-10s %-10s %-10s %-16s %s", "LOOP", "MODE", "STATUS", "ITERATION", "BRANCH", "DIR")
+	lines := []string{
+		lipgloss.NewStyle().Bold(true).Render("Loops"),
+		lipgloss.NewStyle().Faint(true).Render(header),
+	}
+
+	for i, inst := range m.loops {
+		st, eng := inst.state, inst.engine
+
+		iteration := "-"
+		if inst.active() || eng.Completed() > 0 {
+			iteration = fmt.Sprintf("%d", eng.Iteration())
+			if limit := st.GetMaxIterations(); limit > 0 {
+				iteration = fmt.Sprintf("%d/%d", eng.Iteration(), limit)
+			}
+		}
+		dir := st.GetStartOptions().Dir
+		if dir == "" {
+			dir = "."
+		}
+
+		status := eng.Status().String()
+		if inst.prompt != "" {
+			status += " ⌨"
+		}
+		statusCell := lipgloss.NewStyle().Foreground(statusColor(eng.Status())).Render(fmt.Sprintf("%-10s", status))
+
+		marker := "  "
+		name := fmt.Sprintf("%-12s", truncate(st.GetName(), 12))
+		if i == m.active {
+			marker = "> "
+			name = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12")).Render(name)
+		}
+		lines = append(lines, fmt.Sprintf("%s%s %-10s %s %-10s %-16s %s",
+			marker, name, st.GetMode(), statusCell, iteration, truncate(st.GetGitBranch(), 16), dir))
+	}
+
+	return lines
+}
+
+// statusColor returns the color used for a process status.
+func statusColor(status process.Status) lipgloss.Color {
+	switch status {
+	case process.StatusRunning:
+		return lipgloss.Color("10")
+	case process.StatusStopping:
+		return lipgloss.Color("11")
+	case process.StatusPaused:
+		return lipgloss.Color("12")
+	case process.StatusFrozen:
+		return lipgloss.Color("14")
+	case process.StatusStopped:
+		return lipgloss.Color("9")
+	default:
+		return lipgloss.Color("7")
+	}
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
@@ -1,13 +1,18 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
-	"regexp"
+	"path/filepath"
+	"sort"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/alex/ralph-tui/src/lib/ansi"
+	"github.com/alex/ralph-tui/src/lib/loop"
 	"github.com/alex/ralph-tui/src/lib/process"
 	"github.com/alex/ralph-tui/src/lib/state"
 	tea "github.com/charmbracelet/bubbletea"
@@ -20,9 +25,13 @@ type fileCache struct {
 	timestamp time.Time
 }
 
-// Model is the root Bubbletea model.
+// Model is the root Bubbletea model. It drives one or more named loops;
+// state, engine and manager belong to the loop currently shown.
 type Model struct {
+	loops             []*loopInstance
+	active            int // Index of the loop shown
 	state             *state.State
+	engine            *loop.Engine
 	manager           *process.Manager
 	width             int
 	height            int
@@ -36,13 +45,45 @@ type Model struct {
 	specsViewingFile  bool
 	planScrollOffset  int
 	specsScrollOffset int
+	logsWrap          bool
+	logsTimestamps    bool
+	logsLabels        bool
+	logsEnd           int // Index past the last entry shown when scrolled back; 0 follows new output
+	procNodes         []process.ProcessNode
+	procsErr          error
+	procsSelected     int
+	procsPending      *procSignal     // Signal awaiting confirmation
+	worktreePending   *worktreeAction // Worktree action awaiting confirmation
+	showEnv           bool            // Dashboard lists the effective environment
+	sessions          []process.SessionInfo
+	sessionsOpen      bool         // Logs view shows the session picker
+	sessionsSelected  int          // Picker cursor
+	sessionView       *sessionView // Past session shown instead of live logs
+	inputActive       bool         // Keys go to the loop's stdin
+	inputRaw          bool         // Forward each key as typed instead of whole lines
+	inputBuffer       string
+	detachable        bool // Running as a daemon; quitting can detach instead
+	shutdown          bool // The user chose to stop the daemon's loops
 }
 
-// NewModel creates a new TUI model.
-func NewModel(st *state.State, mgr *process.Manager) *Model {
-	return &Model{
-		state:             st,
-		manager:           mgr,
+// sessionView is a past session log opened in the logs view.
+type sessionView struct {
+	name    string
+	entries []process.LogEntry
+	scroll  int // Lines scrolled back from the end
+}
+
+// procSignal is a signal the user chose to send to one process.
+type procSignal struct {
+	pid     int
+	command string
+	signal  syscall.Signal
+}
+
+// NewModel creates a new TUI model showing the given loop. More loops can
+// be added with AddLoop before the program starts.
+func NewModel(st *state.State, eng *loop.Engine) *Model {
+	m := &Model{
 		specsCache:        make(map[string]*fileCache),
 		cacheDuration:     5 * time.Second, // Refresh cache every 5 seconds
 		showQuitConfirm:   false,
@@ -50,15 +91,20 @@ func NewModel(st *state.State, mgr *process.Manager) *Model {
 		specsViewingFile:  false,
 		planScrollOffset:  0,
 		specsScrollOffset: 0,
+		logsLabels:        true,
 	}
+	m.AddLoop(st, eng)
+	m.selectLoop(0)
+	return m
 }
 
 // Init initializes the model.
 func (m *Model) Init() tea.Cmd {
-	return tea.Batch(
-		m.fetchGitBranch(),
-		m.tickForLogs(),
-	)
+	cmds := []tea.Cmd{tickClock()}
+	for _, inst := range m.loops {
+		cmds = append(cmds, fetchGitBranch(inst.state), waitForEvents(inst))
+	}
+	return tea.Batch(cmds...)
 }
 
 // Update handles messages and updates the model.
@@ -68,31 +114,44 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 		m.ready = true
+		// Propagate the log view size to PTY-backed processes
+		for _, inst := range m.loops {
+			_ = inst.engine.Manager().Resize(m.width, m.contentHeight())
+		}
 		return m, nil
 
 	case tea.KeyMsg:
 		return m.handleKeyPress(msg)
 
-	case tickMsg:
-		// Parse logs for iteration markers and batch with next tick
-		parseCmd := m.parseLogsForIterations()
-		tickCmd := m.tickForLogs()
-		if parseCmd != nil {
-			return m, tea.Batch(parseCmd, tickCmd)
+	case eventsMsg:
+		// Sync loop progress into state and wait for the next events
+		m.trackPrompt(msg.loop, msg.events)
+		syncLoopState(msg.loop)
+		return m, waitForEvents(msg.loop)
+
+	case clockMsg:
+		// Redraw so elapsed times advance between events
+		if m.state.GetCurrentView() == "procs" {
+			m.refreshProcs()
 		}
-		return m, tickCmd
+		return m, tickClock()
 
 	case gitBranchMsg:
-		m.state.SetGitBranch(string(msg))
+		msg.state.SetGitBranch(msg.branch)
 		return m, nil
 
-	case iterationMsg:
-		m.state.IncrementIteration()
-		return m, nil
+	case worktreeMsg:
+		return m, m.handleWorktreeMsg(msg)
 
-	case completeMsg:
-		m.state.SetComplete(true)
+	case stopMsg:
+		m.handleStopMsg(msg)
 		return m, nil
+
+	case loopsStoppedMsg:
+		return m, tea.Quit
+
+	case inputMsg:
+		return m, m.handleInputMsg(msg)
 	}
 
 	return m, nil
@@ -100,11 +159,21 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // handleKeyPress processes keyboard input.
 func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// Guard: Stopping the loops to quit
+	if m.shutdown {
+		return m, nil
+	}
+
 	// Handle quit confirmation dialog
 	if m.showQuitConfirm {
 		switch msg.String() {
-		case "y", "Y":
+		case "y", "Y", "s", "S":
 			return m, m.confirmQuit()
+		case "d", "D":
+			if m.detachable {
+				return m, tea.Quit
+			}
+			return m, nil
 		case "n", "N", "q", "ctrl+c":
 			m.showQuitConfirm = false
 			return m, nil
@@ -112,6 +181,11 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	// Operator input goes to the loop, not to the key bindings
+	if m.inputActive {
+		return m, m.handleInputKey(msg)
+	}
+
 	// Handle specs view navigation
 	if m.state.GetCurrentView() == "specs" {
 		if m.specsViewingFile {
@@ -160,6 +234,130 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	}
 
+	// Handle the session picker and past session logs
+	if m.state.GetCurrentView() == "logs" {
+		if m.sessionsOpen {
+			m.handleSessionPickerKey(msg)
+			return m, nil
+		}
+		if m.sessionView != nil {
+			switch msg.String() {
+			case "esc", "backspace":
+				m.sessionView = nil
+				return m, nil
+			case "up", "k":
+				m.sessionView.scroll++
+				return m, nil
+			case "down", "j":
+				if m.sessionView.scroll > 0 {
+					m.sessionView.scroll--
+				}
+				return m, nil
+			case "pgup":
+				m.sessionView.scroll += 10
+				return m, nil
+			case "pgdown":
+				m.sessionView.scroll = max(0, m.sessionView.scroll-10)
+				return m, nil
+			}
+		}
+	}
+
+	// Handle logs view options
+	if m.state.GetCurrentView() == "logs" {
+		switch msg.String() {
+		case "o":
+			m.openSessionPicker()
+			return m, nil
+		case "up", "k":
+			m.scrollLogs(-1)
+			return m, nil
+		case "down", "j":
+			m.scrollLogs(1)
+			return m, nil
+		case "pgup":
+			m.scrollLogs(-m.contentHeight())
+			return m, nil
+		case "pgdown":
+			m.scrollLogs(m.contentHeight())
+			return m, nil
+		case "home", "g":
+			m.logsEnd = min(m.contentHeight(), m.manager.LogCount())
+			return m, nil
+		case "end", "G":
+			m.logsEnd = 0
+			return m, nil
+		case "w":
+			m.logsWrap = !m.logsWrap
+			return m, nil
+		case "t":
+			m.logsTimestamps = !m.logsTimestamps
+			return m, nil
+		case "l":
+			m.logsLabels = !m.logsLabels
+			return m, nil
+		}
+	}
+
+	// Handle dashboard options
+	if m.state.GetCurrentView() == "dashboard" {
+		if m.worktreePending != nil {
+			if key := msg.String(); key == "y" || key == "Y" {
+				return m, m.runWorktreeAction()
+			}
+			// Any other key cancels
+			m.worktreePending = nil
+			return m, nil
+		}
+
+		switch msg.String() {
+		case "e":
+			m.showEnv = !m.showEnv
+			return m, nil
+		case "M":
+			m.confirmWorktreeAction(worktreeAction{})
+			return m, nil
+		case "D":
+			m.confirmWorktreeAction(worktreeAction{remove: true})
+			return m, nil
+		}
+	}
+
+	// Handle process tree selection and signals
+	if m.state.GetCurrentView() == "procs" {
+		if m.procsPending != nil {
+			switch msg.String() {
+			case "y", "Y":
+				m.sendProcSignal()
+			}
+			// Any other key cancels
+			m.procsPending = nil
+			return m, nil
+		}
+
+		switch msg.String() {
+		case "up", "k":
+			if m.procsSelected > 0 {
+				m.procsSelected--
+			}
+			return m, nil
+		case "down", "j":
+			if m.procsSelected < len(m.procNodes)-1 {
+				m.procsSelected++
+			}
+			return m, nil
+		case "I":
+			m.confirmProcSignal(syscall.SIGINT)
+			return m, nil
+		case "T":
+			m.confirmProcSignal(syscall.SIGTERM)
+			return m, nil
+		case "K":
+			m.confirmProcSignal(syscall.SIGKILL)
+			return m, nil
+		}
+	}
+
 	// Handle plan view scrolling
 	if m.state.GetCurrentView() == "plan" {
 		switch msg.String() {
@@ -200,6 +398,24 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "p":
 		return m, m.handlePause()
 
+	case "f":
+		return m, m.handleFreeze()
+
+	case "[":
+		m.selectLoop((m.active + len(m.loops) - 1) % len(m.loops))
+		return m, nil
+
+	case "]":
+		m.selectLoop((m.active + 1) % len(m.loops))
+		return m, nil
+
+	case "i":
+		if m.manager.AcceptsInput() {
+			m.inputActive = true
+			m.inputBuffer = ""
+		}
+		return m, nil
+
 	case "1":
 		m.state.SetCurrentView("dashboard")
 		m.specsViewingFile = false
@@ -227,50 +443,57 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.specsScrollOffset = 0
 		m.specsViewingFile = false
 		return m, nil
+
+	case "5":
+		m.state.SetCurrentView("procs")
+		m.specsViewingFile = false
+		m.procsSelected = 0
+		m.refreshProcs()
+		return m, nil
 	}
 
 	return m, nil
 }
 
-// handleStart starts or resumes the loop process.
+// handleStart starts the loop, resumes it when paused, or cancels a pending
+// stop.
 func (m *Model) handleStart() tea.Cmd {
-	if m.manager.IsRunning() {
+	if m.engine.StopPending() {
+		if err := m.engine.CancelStop(); err != nil {
+			m.state.SetError(err.Error())
+		} else {
+			m.state.ClearError()
+		}
 		return nil
 	}
 
-	mode := m.state.GetMode()
-	maxIter := m.state.GetMaxIterations()
-
-	args := []string{}
-	if mode == state.ModePlan {
-		args = append(args, "plan")
-		if maxIter > 0 {
-			args = append(args, fmt.Sprintf("%d", maxIter))
-		}
-	} else if mode == state.ModePlanWork {
-		args = append(args, "plan-work")
-		args = append(args, m.state.GetWorkDesc())
-		if maxIter > 0 {
-			args = append(args, fmt.Sprintf("%d", maxIter))
-		}
-	} else {
-		// Build mode
-		if maxIter > 0 {
-			args = append(args, fmt.Sprintf("%d", maxIter))
+	// Resume keeps the iteration count and logs intact
+	if m.engine.IsPaused() || m.engine.PausePending() {
+		if err := m.engine.Resume(); err != nil {
+			m.state.SetError(err.Error())
+		} else {
+			m.state.ClearError()
 		}
+		return nil
+	}
+
+	if m.loopActive() {
+		return nil
 	}
 
-	// Only reset iteration if not resuming from pause
-	if !m.manager.IsPaused() {
-		m.state.ResetIteration()
-		m.manager.ClearLogs()
+	// Guard: The loop's checkout was cleaned up
+	if m.loop().worktreeRemoved {
+		m.state.SetError("Worktree removed; restart ralph-tui to run this loop again")
+		return nil
 	}
 
+	m.state.ResetIteration()
+	m.manager.ClearLogs()
+	m.logsEnd = 0
 	m.state.ClearError()
 	m.state.SetComplete(false)
 
-	scriptPath := m.state.GetScriptPath()
-	err := m.manager.Start(scriptPath, args...)
+	err := m.engine.Start(LoopConfig(m.state))
 	if err != nil {
 		m.state.SetError(err.Error())
 	} else {
@@ -280,73 +503,308 @@ func (m *Model) handleStart() tea.Cmd {
 	return nil
 }
 
-// handleStop stops the loop process.
+// LoopConfig builds the configuration a loop is started (or adopted) with
+// from its state.
+func LoopConfig(st *state.State) loop.Config {
+	return loop.Config{
+		Runner:        st.GetRunner(),
+		Mode:          st.GetMode(),
+		MaxIterations: st.GetMaxIterations(),
+		WorkDesc:      st.GetWorkDesc(),
+		ScriptPath:    st.GetScriptPath(),
+		Push:          true,
+		Restart:       st.GetRestartPolicy(),
+		Timeout:       st.GetTimeoutPolicy(),
+		Stall:         st.GetStallPolicy(),
+		Start:         st.GetStartOptions(),
+		Escalation:    st.GetEscalation(),
+	}
+}
+
+// handleStop stops the loop once the current iteration finishes. Pressed
+// again while that stop is pending it stops the loop immediately; a paused
+// or frozen loop is stopped right away.
 func (m *Model) handleStop() tea.Cmd {
-	if !m.manager.IsRunning() {
+	// Guard: A stop is already under way
+	if !m.loopActive() || m.loop().stopping {
 		return nil
 	}
 
-	err := m.manager.Stop()
-	if err != nil {
-		m.state.SetError(err.Error())
+	// Second press: do not wait for the iteration
+	if m.engine.StopPending() {
+		return m.handleStopImmediate()
 	}
-	m.state.SetProcessStatus(process.StatusStopped)
 
-	return nil
+	if m.engine.IsRunning() {
+		if err := m.engine.StopAfterIteration(); err != nil {
+			m.state.SetError(err.Error())
+		} else {
+			m.state.SetError(fmt.Sprintf("Will stop after iteration %d - press 'x' again to stop now, 's' to cancel", m.engine.Iteration()))
+		}
+		return nil
+	}
+
+	return stopLoop(m.loop(), false)
 }
 
 // handleQuit handles application exit with confirmation if process running.
 func (m *Model) handleQuit() tea.Cmd {
-	// If process is running, show confirmation
-	if m.manager.IsRunning() {
+	// A daemon always asks: detaching leaves the loops running
+	if m.anyLoopActive() || m.detachable {
 		m.showQuitConfirm = true
 		return nil
 	}
 	return tea.Quit
 }
 
-// confirmQuit performs the actual quit after confirmation.
+// quitPrompt asks whether to quit while loops are running, or whether to
+// detach from a daemon.
+func (m *Model) quitPrompt() string {
+	running := 0
+	for _, inst := range m.loops {
+		if inst.active() {
+			running++
+		}
+	}
+	if m.detachable {
+		return fmt.Sprintf("%d of %d loops running. d: detach (keep running) | s: stop loops and exit daemon | n: cancel", running, len(m.loops))
+	}
+	if len(m.loops) > 1 {
+		return fmt.Sprintf("%d of %d loops running. Stop them and quit? (y/n)", running, len(m.loops))
+	}
+	return "Process is running. Quit anyway? (y/n)"
+}
+
+// confirmQuit stops the loops after confirmation and quits once they are
+// down.
 func (m *Model) confirmQuit() tea.Cmd {
-	if m.manager.IsRunning() {
-		_ = m.manager.Stop()
+	m.showQuitConfirm = false
+	m.shutdown = true
+	m.state.SetError("Stopping loops...")
+	return func() tea.Msg {
+		m.stopLoops()
+		return loopsStoppedMsg{}
 	}
-	return tea.Quit
 }
 
-// handleStopImmediate sends SIGINT to the process for immediate stop.
+// handleStopImmediate interrupts the process (SIGINT by default) for an
+// immediate stop.
 func (m *Model) handleStopImmediate() tea.Cmd {
-	if !m.manager.IsRunning() {
+	// Guard: A stop is already under way
+	if !m.loopActive() || m.loop().stopping {
+		return nil
+	}
+
+	return stopLoop(m.loop(), true)
+}
+
+// stopLoop stops a loop in the background; stopping waits for the process
+// to go down, which may take the whole escalation ladder.
+func stopLoop(inst *loopInstance, immediate bool) tea.Cmd {
+	inst.stopping = true
+	return func() tea.Msg {
+		stop := inst.engine.Stop
+		if immediate {
+			stop = inst.engine.StopImmediate
+		}
+		return stopMsg{loop: inst, immediate: immediate, err: stop()}
+	}
+}
+
+// handleStopMsg records a finished stop.
+func (m *Model) handleStopMsg(msg stopMsg) {
+	msg.loop.stopping = false
+	st := msg.loop.state
+	if msg.err != nil {
+		st.SetError(msg.err.Error())
+	} else if msg.immediate {
+		st.SetError(fmt.Sprintf("Process interrupted (%s)", process.SignalName(st.GetEscalation().Interrupt[0].Signal)))
+	}
+	st.SetProcessStatus(process.StatusStopped)
+}
+
+// handlePause requests a pause once the current iteration completes.
+func (m *Model) handlePause() tea.Cmd {
+	if !m.engine.IsRunning() || m.engine.PausePending() || m.engine.StopPending() {
 		return nil
 	}
 
-	err := m.manager.StopImmediate()
+	err := m.engine.Pause()
 	if err != nil {
 		m.state.SetError(err.Error())
 	} else {
-		m.state.SetError("Process interrupted (SIGINT)")
+		m.state.SetError(fmt.Sprintf("Will be paused after iteration %d - press 's' to cancel", m.engine.Iteration()))
 	}
-	m.state.SetProcessStatus(process.StatusStopped)
 
 	return nil
 }
 
-// handlePause pauses the loop process.
-func (m *Model) handlePause() tea.Cmd {
-	if !m.manager.IsRunning() {
+// handleFreeze toggles SIGSTOP/SIGCONT on the loop's process group.
+func (m *Model) handleFreeze() tea.Cmd {
+	if m.engine.IsFrozen() {
+		if err := m.engine.Thaw(); err != nil {
+			m.state.SetError(err.Error())
+		} else {
+			m.state.ClearError()
+		}
 		return nil
 	}
 
-	err := m.manager.Pause()
-	if err != nil {
+	if !m.engine.IsRunning() && !m.engine.IsPaused() {
+		return nil
+	}
+
+	if err := m.engine.Freeze(); err != nil {
 		m.state.SetError(err.Error())
 	} else {
-		m.state.SetError("Process paused - press 's' to resume")
+		m.state.SetError("Process frozen (SIGSTOP) - press 'f' to thaw")
+	}
+
+	return nil
+}
+
+// trackPrompt remembers the loop's last output line while it looks like a
+// question, and forgets it once answered or the process exits.
+func (m *Model) trackPrompt(inst *loopInstance, events []process.Event) {
+	for _, ev := range events {
+		switch ev.Kind {
+		case process.EventLog:
+			switch ev.Entry.Stream {
+			case process.StreamStdout, process.StreamStderr:
+				inst.prompt = ""
+				if process.LooksLikePrompt(ev.Entry.Text) {
+					inst.prompt = strings.TrimSpace(ev.Entry.Text)
+				}
+			case process.StreamStdin:
+				inst.prompt = ""
+			}
+		case process.EventExit:
+			inst.prompt = ""
+			if inst == m.loop() {
+				m.inputActive = false
+			}
+		}
+	}
+}
+
+// handleInputKey handles a key while typing to the loop. In line mode keys
+// edit a line sent on enter; in raw mode each key is sent as typed.
+func (m *Model) handleInputKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		m.inputActive = false
+		m.inputBuffer = ""
+		return nil
+	case "tab":
+		m.inputRaw = !m.inputRaw
+		m.inputBuffer = ""
+		return nil
+	case "ctrl+d":
+		m.inputActive = false
+		return m.queueInput(pendingInput{eof: true})
 	}
-	m.state.SetProcessStatus(process.StatusPaused)
 
+	if m.inputRaw {
+		var text string
+		switch msg.Type {
+		case tea.KeyRunes:
+			text = string(msg.Runes)
+		case tea.KeySpace:
+			text = " "
+		case tea.KeyEnter:
+			text = "\n"
+		case tea.KeyBackspace:
+			text = "\x7f"
+		}
+		if text != "" {
+			return m.queueInput(pendingInput{text: text})
+		}
+		return nil
+	}
+
+	switch msg.Type {
+	case tea.KeyEnter:
+		text := m.inputBuffer + "\n"
+		m.inputBuffer = ""
+		m.inputActive = false
+		return m.queueInput(pendingInput{text: text})
+	case tea.KeyBackspace:
+		if runes := []rune(m.inputBuffer); len(runes) > 0 {
+			m.inputBuffer = string(runes[:len(runes)-1])
+		}
+	case tea.KeySpace:
+		m.inputBuffer += " "
+	case tea.KeyRunes:
+		m.inputBuffer += string(msg.Runes)
+	}
 	return nil
 }
 
+// queueInput queues input for the current loop's stdin. A child that stops
+// reading blocks the write, so it never runs on the update loop.
+func (m *Model) queueInput(in pendingInput) tea.Cmd {
+	inst := m.loop()
+	inst.inputQueue = append(inst.inputQueue, in)
+	return writeInput(inst)
+}
+
+// writeInput writes the loop's next queued input in the background. Only one
+// write is in flight at a time so keys reach the process in the order typed.
+func writeInput(inst *loopInstance) tea.Cmd {
+	// Guard: The write in flight sends the next one when done
+	if inst.inputBusy || len(inst.inputQueue) == 0 {
+		return nil
+	}
+
+	in := inst.inputQueue[0]
+	inst.inputQueue = inst.inputQueue[1:]
+	inst.inputBusy = true
+	mgr := inst.engine.Manager()
+	return func() tea.Msg {
+		if in.eof {
+			return inputMsg{loop: inst, eof: true, err: mgr.CloseInput()}
+		}
+		return inputMsg{loop: inst, err: mgr.WriteInput(in.text)}
+	}
+}
+
+// handleInputMsg records a finished write and starts the next one. After a
+// failure the rest of the queue is dropped.
+func (m *Model) handleInputMsg(msg inputMsg) tea.Cmd {
+	inst := msg.loop
+	inst.inputBusy = false
+	if msg.err != nil {
+		inst.inputQueue = nil
+		if msg.eof {
+			inst.state.SetError(fmt.Sprintf("Failed to close input: %v", msg.err))
+		} else {
+			inst.state.SetError(fmt.Sprintf("Failed to send input: %v", msg.err))
+		}
+		if inst == m.loop() {
+			m.inputActive = false
+		}
+		return nil
+	}
+	return writeInput(inst)
+}
+
+// renderInput renders the input line that replaces the footer while typing
+// to the loop.
+func (m *Model) renderInput() string {
+	hints := lipgloss.NewStyle().Faint(true)
+	if m.inputRaw {
+		return promptStyle.Render("RAW input: keys are sent as typed") + "  " +
+			hints.Render("tab:line mode | ctrl+d:EOF | esc:done")
+	}
+	return promptStyle.Render("> "+m.inputBuffer+"█") + "  " +
+		hints.Render("enter:send | tab:raw mode | ctrl+d:EOF | esc:cancel")
+}
+
+// loopActive returns true while the loop owns a run (running, paused or frozen).
+func (m *Model) loopActive() bool {
+	return m.loop().active()
+}
+
 // View renders the UI.
 func (m *Model) View() string {
 	if !m.ready {
@@ -396,13 +854,34 @@ func (m *Model) renderHeader() string {
 		branch = "unknown"
 	}
 
-	status := m.manager.GetStatus().String()
+	status := m.engine.Status().String()
 	statusStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
-	if m.manager.GetStatus() == process.StatusRunning {
+	switch m.engine.Status() {
+	case process.StatusRunning:
 		statusStyle = statusStyle.Foreground(lipgloss.Color("10"))
+	case process.StatusFrozen:
+		statusStyle = statusStyle.Foreground(lipgloss.Color("14"))
+	}
+
+	if m.engine.PausePending() {
+		status = fmt.Sprintf("%s (pausing after iteration %d)", status, m.engine.Iteration())
+	}
+	if m.engine.StopPending() {
+		status = fmt.Sprintf("Stopping after iteration %d", m.engine.Iteration())
+		statusStyle = statusStyle.Foreground(lipgloss.Color("11"))
 	}
 
 	info := fmt.Sprintf("Branch: %s | Status: %s", branch, statusStyle.Render(status))
+	if len(m.loops) > 1 {
+		info = fmt.Sprintf("Loop: %s (%d/%d) | %s", m.state.GetName(), m.active+1, len(m.loops), info)
+	}
+
+	if quiet, stalled := m.manager.Stalled(); stalled {
+		info += " | " + stallStyle.Render(fmt.Sprintf("⚠ STALLED %s", quiet.Truncate(time.Second)))
+	}
+	if m.loop().prompt != "" {
+		info += " | " + promptStyle.Render("⌨ WAITING FOR INPUT")
+	}
 
 	return fmt.Sprintf("%s    %s", title, info)
 }
@@ -411,8 +890,8 @@ func (m *Model) renderHeader() string {
 func (m *Model) renderTabs() string {
 	currentView := m.state.GetCurrentView()
 
-	tabs := []string{"1:Dashboard", "2:Logs", "3:Plan", "4:Specs"}
-	views := []string{"dashboard", "logs", "plan", "specs"}
+	tabs := []string{"1:Dashboard", "2:Logs", "3:Plan", "4:Specs", "5:Procs"}
+	views := []string{"dashboard", "logs", "plan", "specs", "procs"}
 
 	var rendered []string
 	for i, tab := range tabs {
@@ -426,9 +905,14 @@ func (m *Model) renderTabs() string {
 	return strings.Join(rendered, " ")
 }
 
+// contentHeight returns the rows available to the current view.
+func (m *Model) contentHeight() int {
+	return m.height - 6 // Reserve space for header, tabs, footer
+}
+
 // renderContent renders the current view content.
 func (m *Model) renderContent() string {
-	contentHeight := m.height - 6 // Reserve space for header, tabs, footer
+	contentHeight := m.contentHeight()
 
 	switch m.state.GetCurrentView() {
 	case "dashboard":
@@ -439,6 +923,8 @@ func (m *Model) renderContent() string {
 		return m.renderPlan(contentHeight)
 	case "specs":
 		return m.renderSpecs(contentHeight)
+	case "procs":
+		return m.renderProcs(contentHeight)
 	default:
 		return "Unknown view"
 	}
@@ -451,8 +937,28 @@ func (m *Model) renderDashboard(height int) string {
 	lines = append(lines, lipgloss.NewStyle().Bold(true).Render("Status Dashboard"))
 	lines = append(lines, "")
 
+	// Every loop at a glance; the rest of the dashboard is the shown loop's
+	if len(m.loops) > 1 {
+		lines = append(lines, m.renderLoopsOverview()...)
+		lines = append(lines, "")
+	}
+
+	// Stall warning banner
+	if quiet, stalled := m.manager.Stalled(); stalled {
+		banner := fmt.Sprintf(" ⚠ No agent output for %s (action: %s) ", quiet.Truncate(time.Second), m.state.GetStallPolicy().Action)
+		lines = append(lines, stallStyle.Reverse(true).Render(banner))
+		lines = append(lines, "")
+	}
+
+	// The loop asked a question and waits for an answer
+	if prompt := m.loop().prompt; prompt != "" {
+		banner := fmt.Sprintf(" ⌨ %s  (press 'i' to answer) ", prompt)
+		lines = append(lines, promptStyle.Reverse(true).Render(banner))
+		lines = append(lines, "")
+	}
+
 	// Process status with color
-	status := m.manager.GetStatus()
+	status := m.engine.Status()
 	statusStr := status.String()
 	statusStyle := lipgloss.NewStyle()
 
@@ -463,6 +969,8 @@ func (m *Model) renderDashboard(height int) string {
 		statusStyle = statusStyle.Foreground(lipgloss.Color("11"))
 	case process.StatusPaused:
 		statusStyle = statusStyle.Foreground(lipgloss.Color("12"))
+	case process.StatusFrozen:
+		statusStyle = statusStyle.Foreground(lipgloss.Color("14"))
 	case process.StatusStopped:
 		statusStyle = statusStyle.Foreground(lipgloss.Color("9"))
 	}
@@ -474,7 +982,7 @@ func (m *Model) renderDashboard(height int) string {
 	lines = append(lines, fmt.Sprintf("Mode: %s", mode))
 
 	// Iteration count
-	if m.manager.IsRunning() || m.manager.IsPaused() {
+	if m.loopActive() {
 		iter := m.state.GetCurrentIteration()
 		maxIter := m.state.GetMaxIterations()
 		if maxIter > 0 {
@@ -482,6 +990,10 @@ func (m *Model) renderDashboard(height int) string {
 		} else {
 			lines = append(lines, fmt.Sprintf("Iteration: %d (unlimited)", iter))
 		}
+
+		if elapsed, ok := m.engine.IterationElapsed(); ok {
+			lines = append(lines, m.renderIterationTime(elapsed))
+		}
 	}
 
 	// Work description for plan-work mode
@@ -493,6 +1005,38 @@ func (m *Model) renderDashboard(height int) string {
 	branch := m.state.GetGitBranch()
 	lines = append(lines, fmt.Sprintf("Branch: %s", branch))
 
+	// Git worktree the loop runs in
+	lines = append(lines, m.renderWorktree()...)
+
+	// Start options of the loop process
+	lines = append(lines, m.renderStartOptions()...)
+
+	// Where this session's output is persisted
+	if log := m.manager.SessionLog(); log != nil {
+		if err := log.Err(); err != nil {
+			lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("9")).
+				Render(fmt.Sprintf("Session log: %s (%v)", log.Path(), err)))
+		} else {
+			lines = append(lines, fmt.Sprintf("Session log: %s", log.Path()))
+		}
+	}
+
+	// Resource usage of the running process group
+	if m.loopActive() {
+		if usage, ok := m.manager.Usage(); ok {
+			lines = append(lines, "")
+			lines = append(lines, m.renderUsage(usage, m.manager.UsageHistory())...)
+		}
+	}
+
+	// Automatic restarts of a crashed loop
+	lines = append(lines, m.renderRestarts()...)
+
+	// Outcome of the most recent agent/script run
+	if exit, ok := m.manager.LastExit(); ok {
+		lines = append(lines, m.renderExit(exit)...)
+	}
+
 	// Completion status
 	if m.state.GetComplete() {
 		lines = append(lines, "")
@@ -508,6 +1052,14 @@ func (m *Model) renderDashboard(height int) string {
 			lines = append(lines, lipgloss.NewStyle().
 				Foreground(lipgloss.Color("12")).
 				Render(errMsg))
+		} else if strings.Contains(errMsg, "frozen") {
+			lines = append(lines, lipgloss.NewStyle().
+				Foreground(lipgloss.Color("14")).
+				Render(errMsg))
+		} else if strings.Contains(errMsg, "stop after") || strings.Contains(errMsg, "stopped after") {
+			lines = append(lines, lipgloss.NewStyle().
+				Foreground(lipgloss.Color("11")).
+				Render(errMsg))
 		} else {
 			lines = append(lines, lipgloss.NewStyle().
 				Foreground(lipgloss.Color("9")).
@@ -515,24 +1067,505 @@ func (m *Model) renderDashboard(height int) string {
 		}
 	}
 
+	// Effective environment in the remaining rows
+	if m.showEnv {
+		lines = append(lines, "")
+		lines = append(lines, m.renderEnvironment(height-len(lines))...)
+	}
+
 	return strings.Join(lines, "\n")
 }
 
-// renderLogs renders the logs view.
+// renderStartOptions renders the working directory, extra arguments and a
+// summary of environment overrides.
+func (m *Model) renderStartOptions() []string {
+	opts := m.state.GetStartOptions()
+	var lines []string
+
+	if opts.Dir != "" {
+		lines = append(lines, fmt.Sprintf("Working dir: %s", opts.Dir))
+	}
+	if len(opts.ExtraArgs) > 0 {
+		lines = append(lines, fmt.Sprintf("Extra args: %s", strings.Join(opts.ExtraArgs, " ")))
+	}
+
+	escalation := m.state.GetEscalation()
+	lines = append(lines, fmt.Sprintf("Stop signals: %s (x), %s (X)", escalation.Stop, escalation.Interrupt))
+
+	summary := fmt.Sprintf("Environment: %d set, %d unset", len(opts.Env), len(opts.Unset))
+	hint := " (e: show)"
+	if m.showEnv {
+		hint = " (e: hide)"
+	}
+	return append(lines, summary+lipgloss.NewStyle().Faint(true).Render(hint))
+}
+
+// renderEnvironment lists the environment the loop process starts with,
+// secret values masked and overrides highlighted, in at most height rows.
+func (m *Model) renderEnvironment(height int) []string {
+	opts := m.state.GetStartOptions()
+	overrides := make(map[string]bool, len(opts.Env))
+	for _, entry := range opts.Env {
+		name, _, _ := strings.Cut(entry, "=")
+		overrides[name] = true
+	}
+
+	lines := []string{lipgloss.NewStyle().Bold(true).Render("Effective environment (secrets masked)")}
+	overridden := lipgloss.NewStyle().Foreground(lipgloss.Color("12"))
+	for _, entry := range process.MaskEnv(opts.Environ(os.Environ())) {
+		name, _, _ := strings.Cut(entry, "=")
+		if overrides[name] {
+			entry = overridden.Render(entry)
+		}
+		lines = append(lines, "  "+entry)
+	}
+	for _, name := range opts.Unset {
+		lines = append(lines, lipgloss.NewStyle().Faint(true).Strikethrough(true).Render("  "+name))
+	}
+
+	if height < 2 {
+		return nil
+	}
+	if len(lines) > height {
+		hidden := len(lines) - height + 1
+		lines = append(lines[:height-1], lipgloss.NewStyle().Faint(true).Render(fmt.Sprintf("  … %d more", hidden)))
+	}
+	return lines
+}
+
+// workPath resolves a project file against the loop's working directory.
+func (m *Model) workPath(elem ...string) string {
+	return filepath.Join(append([]string{m.state.GetStartOptions().Dir}, elem...)...)
+}
+
+// maxUsageProcesses is how many processes the dashboard lists, busiest first.
+const maxUsageProcesses = 5
+
+// sparkTicks are the bar heights used for history sparklines.
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// renderUsage renders aggregate resource usage with a CPU history sparkline
+// and the busiest processes of the group.
+func (m *Model) renderUsage(usage process.UsageSample, history []process.UsageSample) []string {
+	lines := []string{fmt.Sprintf("Resources: CPU %.1f%% | RSS %s | FDs %d | %d processes",
+		usage.CPUPercent, formatBytes(usage.RSS), usage.FDs, len(usage.Processes))}
+
+	cpu := make([]float64, len(history))
+	rss := make([]float64, len(history))
+	for i, sample := range history {
+		cpu[i] = sample.CPUPercent
+		rss[i] = float64(sample.RSS)
+	}
+	faint := lipgloss.NewStyle().Faint(true)
+	lines = append(lines, fmt.Sprintf("  CPU %s", lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render(sparkline(cpu))))
+	lines = append(lines, fmt.Sprintf("  RSS %s", lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Render(sparkline(rss))))
+
+	procs := make([]process.ProcessUsage, len(usage.Processes))
+	copy(procs, usage.Processes)
+	sort.SliceStable(procs, func(i, j int) bool { return procs[i].CPUPercent > procs[j].CPUPercent })
+	if len(procs) > maxUsageProcesses {
+		procs = procs[:maxUsageProcesses]
+	}
+
+	lines = append(lines, faint.Render(fmt.Sprintf("  %7s %6s %10s %5s  %s", "PID", "CPU%", "RSS", "FDs", "COMMAND")))
+	for _, proc := range procs {
+		line := fmt.Sprintf("  %7d %6.1f %10s %5d  %s", proc.PID, proc.CPUPercent, formatBytes(proc.RSS), proc.FDs, proc.Command)
+		lines = append(lines, ansi.Parse(line).Truncate(m.width, "…").Plain())
+	}
+
+	return lines
+}
+
+// sparkline scales values to bar heights relative to the largest one.
+func sparkline(values []float64) string {
+	peak := 0.0
+	for _, v := range values {
+		peak = max(peak, v)
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		idx := 0
+		if peak > 0 {
+			idx = int(v / peak * float64(len(sparkTicks)-1))
+		}
+		b.WriteRune(sparkTicks[idx])
+	}
+	return b.String()
+}
+
+// formatBytes renders a byte count with a binary unit, e.g. "512.3 MiB".
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// stallStyle highlights stall warnings.
+var stallStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("11"))
+
+// promptStyle highlights a loop waiting for operator input.
+var promptStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+
+// renderIterationTime renders the running iteration's elapsed time against
+// the watchdog limit, turning yellow near the limit and red past it.
+func (m *Model) renderIterationTime(elapsed time.Duration) string {
+	elapsed = elapsed.Truncate(time.Second)
+	limit := m.state.GetTimeoutPolicy().Limit
+	if limit <= 0 {
+		return fmt.Sprintf("Iteration Time: %s", elapsed)
+	}
+
+	style := lipgloss.NewStyle()
+	switch {
+	case elapsed >= limit:
+		style = style.Foreground(lipgloss.Color("9"))
+	case elapsed >= limit*4/5:
+		style = style.Foreground(lipgloss.Color("11"))
+	}
+	return fmt.Sprintf("Iteration Time: %s / %s (on timeout: %s)",
+		style.Render(elapsed.String()), limit, m.state.GetTimeoutPolicy().Action)
+}
+
+// maxRestartLines is how many recent restarts the dashboard lists.
+const maxRestartLines = 3
+
+// renderRestarts renders the restart policy and the most recent restarts.
+func (m *Model) renderRestarts() []string {
+	policy := m.state.GetRestartPolicy()
+	restarts := m.engine.Restarts()
+	if policy.Mode == state.RestartNever && len(restarts) == 0 {
+		return nil
+	}
+
+	limit := "unlimited"
+	if policy.MaxRestarts > 0 {
+		limit = fmt.Sprintf("max %d", policy.MaxRestarts)
+	}
+	lines := []string{fmt.Sprintf("Restarts: %d (%s, %s)", len(restarts), policy.Mode, limit)}
+
+	warn := lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+	start := max(0, len(restarts)-maxRestartLines)
+	for _, restart := range restarts[start:] {
+		line := ansi.Parse(fmt.Sprintf("  %s #%d at iteration %d after %s: %s",
+			restart.Time.Format("15:04:05"), restart.Attempt, restart.Iteration, restart.Delay, restart.Reason))
+		lines = append(lines, warn.Render(line.Truncate(m.width, "…").Plain()))
+	}
+	return lines
+}
+
+// renderExit renders the last run's outcome and, unless it succeeded, the
+// stderr lines leading up to it.
+func (m *Model) renderExit(exit process.ExitInfo) []string {
+	outcomeStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	if exit.Requested {
+		outcomeStyle = outcomeStyle.Foreground(lipgloss.Color("11"))
+	} else if !exit.Success() {
+		outcomeStyle = outcomeStyle.Foreground(lipgloss.Color("9"))
+	}
+
+	lines := []string{fmt.Sprintf("Last Run: %s after %s",
+		outcomeStyle.Render(exit.String()),
+		exit.Duration().Round(time.Second))}
+
+	if exit.Success() || len(exit.Stderr) == 0 {
+		return lines
+	}
+
+	faint := lipgloss.NewStyle().Faint(true)
+	for _, text := range exit.Stderr {
+		line := ansi.Parse(text).Prepend("", "  │ ").Truncate(m.width, "…")
+		lines = append(lines, faint.Render(line.Plain()))
+	}
+	return lines
+}
+
+// refreshProcs re-reads the process tree, keeping the selection on the same
+// PID when it still exists.
+func (m *Model) refreshProcs() {
+	selected := 0
+	if m.procsSelected < len(m.procNodes) {
+		selected = m.procNodes[m.procsSelected].PID
+	}
+
+	m.procNodes, m.procsErr = m.manager.ProcessTree()
+
+	m.procsSelected = min(m.procsSelected, max(len(m.procNodes)-1, 0))
+	for i, node := range m.procNodes {
+		if node.PID == selected {
+			m.procsSelected = i
+			break
+		}
+	}
+}
+
+// confirmProcSignal asks before sending sig to the selected process.
+func (m *Model) confirmProcSignal(sig syscall.Signal) {
+	if m.procsSelected >= len(m.procNodes) {
+		return
+	}
+	node := m.procNodes[m.procsSelected]
+	m.procsPending = &procSignal{pid: node.PID, command: node.Command, signal: sig}
+}
+
+// sendProcSignal delivers the confirmed signal to a single process.
+func (m *Model) sendProcSignal() {
+	pending := m.procsPending
+	if err := m.manager.SignalProcess(pending.pid, pending.signal); err != nil {
+		m.state.SetError(err.Error())
+	}
+	m.refreshProcs()
+}
+
+// renderProcs renders the process tree of the running loop.
+func (m *Model) renderProcs(height int) string {
+	if m.procsErr != nil {
+		if errors.Is(m.procsErr, process.ErrNotRunning) {
+			return "No process running. Press 's' to start the loop."
+		}
+		return fmt.Sprintf("Failed to read process tree: %v", m.procsErr)
+	}
+	if len(m.procNodes) == 0 {
+		return "No processes found."
+	}
+
+	var lines []string
+	lines = append(lines, lipgloss.NewStyle().Faint(true).Render(
+		"(↑↓:select, I:SIGINT, T:SIGTERM, K:SIGKILL the selected process)"))
+	lines = append(lines, lipgloss.NewStyle().Bold(true).Render(
+		fmt.Sprintf("%7s %1s %9s  %s", "PID", "S", "TIME", "COMMAND")))
+	height -= len(lines)
+
+	// Keep the selection in view
+	start := 0
+	if m.procsSelected >= height {
+		start = m.procsSelected - height + 1
+	}
+
+	for i := start; i < len(m.procNodes) && i < start+height; i++ {
+		node := m.procNodes[i]
+
+		command := strings.Join(node.Args, " ")
+		if command == "" {
+			command = "[" + node.Command + "]"
+		}
+		branch := ""
+		if node.Depth > 0 {
+			branch = strings.Repeat("  ", node.Depth-1) + "└─ "
+		}
+
+		line := fmt.Sprintf("%7d %c %9s  %s%s", node.PID, node.State,
+			node.Runtime().Truncate(time.Second), branch, command)
+		line = ansi.Parse(line).Truncate(m.width, "…").Plain()
+
+		if i == m.procsSelected {
+			line = lipgloss.NewStyle().Reverse(true).Render(line)
+		} else if node.State == 'Z' || node.State == 'T' {
+			line = lipgloss.NewStyle().Faint(true).Render(line)
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// renderLogs renders the logs view. Agent colors are kept per line; long
+// lines are truncated to the terminal width, or wrapped when enabled.
 func (m *Model) renderLogs(height int) string {
-	logs := m.manager.GetLogs()
+	if m.sessionsOpen {
+		return m.renderSessionPicker(height)
+	}
+	if m.sessionView != nil {
+		return m.renderSessionView(height)
+	}
+
+	// Every entry takes at least one line, so the tail always fills the view
+	var logs []process.LogEntry
+	hint := "(w:wrap, t:timestamps, l:stream labels, up/down:scroll, o:past sessions)"
+	if m.logsEnd > 0 {
+		total := m.manager.LogCount()
+		m.logsEnd = min(m.logsEnd, total)
+		logs = m.manager.ReadLogs(m.logsEnd-height, height)
+		hint = fmt.Sprintf("Scrolled back: line %d of %d (up/down/pgup/pgdown:scroll, G:follow)", m.logsEnd, total)
+	} else {
+		logs = m.manager.TailLogs(height)
+	}
 
 	if len(logs) == 0 {
 		return "No logs yet. Press 's' to start the loop."
 	}
 
-	// Show last N lines that fit in view
-	start := 0
-	if len(logs) > height {
-		start = len(logs) - height
+	return m.renderLogLines(logs, lipgloss.NewStyle().Faint(true).Render(hint), height)
+}
+
+// scrollLogs moves the live logs view by delta entries; scrolling past the
+// newest entry follows new output again.
+func (m *Model) scrollLogs(delta int) {
+	total := m.manager.LogCount()
+	end := m.logsEnd
+	if end == 0 {
+		end = total
+	}
+
+	end += delta
+	if end >= total {
+		m.logsEnd = 0
+		return
+	}
+	m.logsEnd = max(end, min(1, total))
+}
+
+// renderLogLines renders the newest entries that fit below a hint line.
+func (m *Model) renderLogLines(logs []process.LogEntry, hint string, height int) string {
+	height-- // Reserve the hint line
+
+	// Interleave stdout and stderr the way they were read, not the way
+	// their goroutines happened to reach the buffer
+	process.SortByCapture(logs)
+
+	// Walk back from the newest line until the view is full
+	var rendered []string
+	for i := len(logs) - 1; i >= 0 && len(rendered) < height; i-- {
+		line := m.formatLogEntry(logs[i])
+
+		display := []ansi.Line{line.Truncate(m.width, "…")}
+		if m.logsWrap {
+			display = line.Wrap(m.width)
+		}
+
+		for j := len(display) - 1; j >= 0 && len(rendered) < height; j-- {
+			rendered = append(rendered, display[j].String())
+		}
+	}
+
+	// Restore chronological order
+	for i, j := 0, len(rendered)-1; i < j; i, j = i+1, j-1 {
+		rendered[i], rendered[j] = rendered[j], rendered[i]
+	}
+
+	return hint + "\n" + strings.Join(rendered, "\n")
+}
+
+// openSessionPicker lists the session logs on disk for the picker.
+func (m *Model) openSessionPicker() {
+	dir := m.state.GetLogDir()
+	if dir == "" {
+		m.state.SetError("Session logs are disabled")
+		return
+	}
+
+	sessions, err := process.ListSessions(dir)
+	if err != nil {
+		m.state.SetError(fmt.Sprintf("Failed to list sessions: %v", err))
+		return
+	}
+	m.sessions = sessions
+	m.sessionsSelected = 0
+	m.sessionsOpen = true
+}
+
+// handleSessionPickerKey moves through the session list and opens the
+// selected session.
+func (m *Model) handleSessionPickerKey(msg tea.KeyMsg) {
+	switch msg.String() {
+	case "esc", "o", "q":
+		m.sessionsOpen = false
+	case "up", "k":
+		if m.sessionsSelected > 0 {
+			m.sessionsSelected--
+		}
+	case "down", "j":
+		if m.sessionsSelected < len(m.sessions)-1 {
+			m.sessionsSelected++
+		}
+	case "enter":
+		if m.sessionsSelected >= len(m.sessions) {
+			return
+		}
+		session := m.sessions[m.sessionsSelected]
+		entries, err := process.ReadSession(session)
+		if err != nil {
+			m.state.SetError(fmt.Sprintf("Failed to read session %s: %v", session.Name, err))
+			return
+		}
+		m.sessionView = &sessionView{name: session.Name, entries: entries}
+		m.sessionsOpen = false
+	}
+}
+
+// renderSessionPicker lists past sessions, newest first.
+func (m *Model) renderSessionPicker(height int) string {
+	lines := []string{lipgloss.NewStyle().Bold(true).Render("Session logs in " + m.state.GetLogDir())}
+	if len(m.sessions) == 0 {
+		return lines[0] + "\n\nNo sessions yet."
+	}
+
+	current := ""
+	if log := m.manager.SessionLog(); log != nil {
+		current = log.Name()
+	}
+
+	for i, session := range m.sessions {
+		if len(lines) >= height {
+			break
+		}
+		line := fmt.Sprintf("%s  %8s  %d part(s)  last written %s",
+			session.Name, formatBytes(uint64(session.Size)), len(session.Files), session.UpdatedAt.Format("2006-01-02 15:04"))
+		if session.Name == current {
+			line += " (this session)"
+		}
+		if i == m.sessionsSelected {
+			line = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12")).Render("> " + line)
+		} else {
+			line = "  " + line
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderSessionView renders a past session, scrolled back from its end.
+func (m *Model) renderSessionView(height int) string {
+	view := m.sessionView
+	view.scroll = min(view.scroll, max(0, len(view.entries)-1))
+	logs := view.entries[:len(view.entries)-view.scroll]
+
+	hint := lipgloss.NewStyle().Faint(true).Render(fmt.Sprintf(
+		"Session %s, %d lines (up/down:scroll, esc:back to live logs)", view.name, len(view.entries)))
+	if len(logs) == 0 {
+		return hint + "\n\nEmpty session."
+	}
+	return m.renderLogLines(logs, hint, height)
+}
+
+// formatLogEntry parses an entry's text and adds the enabled stream label
+// and timestamp in front of it.
+func (m *Model) formatLogEntry(entry process.LogEntry) ansi.Line {
+	line := ansi.Parse(entry.Text)
+
+	if m.logsLabels {
+		switch entry.Stream {
+		case process.StreamStdout:
+			line = line.Prepend("", "[OUT] ")
+		case process.StreamStderr:
+			line = line.Prepend("31", "[ERR] ")
+		case process.StreamStdin:
+			line = line.Prepend("36", "[IN]  ")
+		}
+	}
+
+	if m.logsTimestamps {
+		line = line.Prepend("2", entry.Time.Format("15:04:05.000")+" ")
 	}
 
-	return strings.Join(logs[start:], "\n")
+	return line
 }
 
 // renderPlan renders the plan view with scrolling support.
@@ -546,7 +1579,7 @@ func (m *Model) renderPlan(height int) string {
 		content = m.planCache.content
 	} else {
 		// Cache miss or expired - read from disk
-		data, err := os.ReadFile("IMPLEMENTATION_PLAN.md")
+		data, err := os.ReadFile(m.workPath("IMPLEMENTATION_PLAN.md"))
 		if err != nil {
 			return "No implementation plan found.\n\nRun './loop.sh plan' to create one."
 		}
@@ -588,7 +1621,7 @@ func (m *Model) renderPlan(height int) string {
 
 // renderSpecs renders the specs view with selectable file list.
 func (m *Model) renderSpecs(height int) string {
-	entries, err := os.ReadDir("specs")
+	entries, err := os.ReadDir(m.workPath("specs"))
 	if err != nil {
 		return "No specs directory found."
 	}
@@ -618,7 +1651,7 @@ func (m *Model) renderSpecs(height int) string {
 			content = cache.content
 		} else {
 			// Cache miss or expired - read from disk
-			data, err := os.ReadFile(fmt.Sprintf("specs/%s", selectedFile))
+			data, err := os.ReadFile(m.workPath("specs", selectedFile))
 			if err == nil {
 				content = string(data)
 				// Update cache
@@ -682,86 +1715,163 @@ func (m *Model) renderFooter() string {
 	if m.showQuitConfirm {
 		return lipgloss.NewStyle().
 			Foreground(lipgloss.Color("11")).
-			Render("Process is running. Quit anyway? (y/n)")
+			Render(m.quitPrompt())
+	}
+
+	// Show worktree merge/cleanup confirmation
+	if m.worktreePending != nil {
+		return lipgloss.NewStyle().
+			Foreground(lipgloss.Color("11")).
+			Render(m.worktreePrompt())
+	}
+
+	// Show signal confirmation in the process tree
+	if m.procsPending != nil {
+		return lipgloss.NewStyle().
+			Foreground(lipgloss.Color("11")).
+			Render(fmt.Sprintf("Send %s to %d (%s)? (y/n)",
+				process.SignalName(m.procsPending.signal), m.procsPending.pid, m.procsPending.command))
+	}
+
+	// Show the input line while typing to the loop
+	if m.inputActive {
+		return m.renderInput()
 	}
 
 	var keys []string
 
-	if m.manager.IsRunning() {
-		keys = append(keys, "x:stop(graceful)", "X:stop(immediate)", "p:pause")
-	} else if m.manager.IsPaused() {
-		keys = append(keys, "s:resume")
+	if m.engine.IsFrozen() {
+		keys = append(keys, "f:thaw", "x:stop(graceful)", "X:stop(immediate)")
+	} else if m.engine.StopPending() {
+		keys = append(keys, "x:stop now", "s:cancel stop", "f:freeze")
+		if m.manager.AcceptsInput() {
+			keys = append(keys, "i:input")
+		}
+	} else if m.engine.PausePending() {
+		keys = append(keys, "x:stop after iteration", "X:stop(immediate)", "s:cancel pause", "f:freeze")
+	} else if m.engine.IsRunning() {
+		keys = append(keys, "x:stop after iteration", "X:stop(immediate)", "p:pause", "f:freeze")
+		if m.manager.AcceptsInput() {
+			keys = append(keys, "i:input")
+		}
+	} else if m.engine.IsPaused() {
+		keys = append(keys, "s:resume", "x:stop")
 	} else {
 		keys = append(keys, "s:start")
 	}
 
-	keys = append(keys, "1-4:tabs", "q:quit")
+	if len(m.loops) > 1 {
+		keys = append(keys, "[/]:loop")
+	}
+	keys = append(keys, "1-5:tabs", "q:quit")
 
 	return lipgloss.NewStyle().
 		Faint(true).
 		Render(strings.Join(keys, " | "))
 }
 
-// fetchGitBranch fetches the current git branch.
-func (m *Model) fetchGitBranch() tea.Cmd {
+// fetchGitBranch fetches the current git branch of a loop's checkout.
+func fetchGitBranch(st *state.State) tea.Cmd {
+	dir := st.GetStartOptions().Dir
 	return func() tea.Msg {
 		cmd := exec.Command("git", "branch", "--show-current")
+		cmd.Dir = dir
 		output, err := cmd.Output()
 		if err != nil {
-			return gitBranchMsg("unknown")
+			return gitBranchMsg{st, "unknown"}
 		}
 		branch := strings.TrimSpace(string(output))
 		// Handle detached HEAD state
 		if branch == "" {
 			// Try to get the commit hash
 			cmd = exec.Command("git", "rev-parse", "--short", "HEAD")
+			cmd.Dir = dir
 			output, err = cmd.Output()
 			if err != nil {
-				return gitBranchMsg("detached HEAD")
+				return gitBranchMsg{st, "detached HEAD"}
 			}
 			branch = "detached@" + strings.TrimSpace(string(output))
 		}
-		return gitBranchMsg(branch)
+		return gitBranchMsg{st, branch}
 	}
 }
 
-// tickForLogs polls for new logs and parses iteration markers.
-func (m *Model) tickForLogs() tea.Cmd {
-	return tea.Tick(200*time.Millisecond, func(t time.Time) tea.Msg {
-		return tickMsg(t)
-	})
-}
+// maxEventBatch caps how many queued events are folded into one redraw.
+const maxEventBatch = 256
 
-// parseLogsForIterations checks logs for iteration markers and completion.
-func (m *Model) parseLogsForIterations() tea.Cmd {
-	logs := m.manager.GetLogs()
-	if len(logs) == 0 {
-		return nil
+// waitForEvents blocks until a loop's manager publishes an event, then
+// collects whatever else is already queued so a burst of output causes one
+// redraw.
+func waitForEvents(inst *loopInstance) tea.Cmd {
+	events := inst.events.Events()
+	return func() tea.Msg {
+		ev, ok := <-events
+		if !ok {
+			return nil
+		}
+
+		batch := []process.Event{ev}
+		for len(batch) < maxEventBatch {
+			select {
+			case ev, ok := <-events:
+				if !ok {
+					return eventsMsg{inst, batch}
+				}
+				batch = append(batch, ev)
+			default:
+				return eventsMsg{inst, batch}
+			}
+		}
+		return eventsMsg{inst, batch}
 	}
+}
 
-	// Check last few lines for iteration markers
-	iterRegex := regexp.MustCompile(`LOOP (\d+)`)
-	completeRegex := regexp.MustCompile(`<promise>COMPLETE</promise>`)
+// tickClock schedules the next once-a-second redraw.
+func tickClock() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+		return clockMsg(t)
+	})
+}
 
-	start := len(logs) - 10
-	if start < 0 {
-		start = 0
-	}
+// syncLoopState copies iteration, completion and failure from a loop's
+// engine into its state.
+func syncLoopState(inst *loopInstance) {
+	st, eng := inst.state, inst.engine
+	st.SetCurrentIteration(eng.Iteration())
+	st.SetProcessStatus(eng.Status())
 
-	for _, line := range logs[start:] {
-		if matches := iterRegex.FindStringSubmatch(line); matches != nil {
-			return func() tea.Msg { return iterationMsg{} }
-		}
-		if completeRegex.MatchString(line) {
-			return func() tea.Msg { return completeMsg{} }
-		}
+	if eng.IsComplete() {
+		st.SetComplete(true)
+	}
+	if eng.IsPaused() {
+		st.SetError(fmt.Sprintf("Process paused after iteration %d - press 's' to resume", eng.Completed()))
+	}
+	if eng.Status() == process.StatusStopped && strings.HasPrefix(st.GetError(), "Will stop after") {
+		st.SetError(fmt.Sprintf("Loop stopped after iteration %d", eng.Completed()))
+	}
+	if err := eng.Err(); err != nil {
+		st.SetError(err.Error())
 	}
-
-	return nil
 }
 
 // Message types
-type tickMsg time.Time
-type gitBranchMsg string
-type iterationMsg struct{}
-type completeMsg struct{}
+type eventsMsg struct {
+	loop   *loopInstance
+	events []process.Event
+}
+type clockMsg time.Time
+type gitBranchMsg struct {
+	state  *state.State
+	branch string
+}
+type stopMsg struct {
+	loop      *loopInstance
+	immediate bool
+	err       error
+}
+type loopsStoppedMsg struct{}
+type inputMsg struct {
+	loop *loopInstance
+	eof  bool
+	err  error
+}
@@ -0,0 +1,131 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/alex/ralph-tui/src/lib/worktree"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// worktreeAction is a merge-back or cleanup the user asked for.
+type worktreeAction struct {
+	remove bool // Remove the worktree instead of merging it back
+	force  bool // Remove even with uncommitted or unmerged work
+}
+
+// worktreeMsg reports the outcome of a worktree action.
+type worktreeMsg struct {
+	loop    *loopInstance
+	action  worktreeAction
+	err     error
+	pushErr error // The merge went through but pushing it did not
+}
+
+// confirmWorktreeAction asks before merging back or removing the shown
+// loop's worktree. The loop must not be running.
+func (m *Model) confirmWorktreeAction(action worktreeAction) {
+	if _, ok := m.state.GetWorktree(); !ok || m.loop().active() || m.loop().worktreeBusy {
+		return
+	}
+	m.worktreePending = &action
+}
+
+// runWorktreeAction performs the pending worktree action in the background.
+func (m *Model) runWorktreeAction() tea.Cmd {
+	action := *m.worktreePending
+	m.worktreePending = nil
+
+	inst := m.loop()
+	wt, ok := inst.state.GetWorktree()
+	if !ok {
+		return nil
+	}
+	inst.worktreeBusy = true
+	inst.worktreeNote = ""
+
+	return func() tea.Msg {
+		repo, err := worktree.Open(wt.Root)
+		if err != nil {
+			return worktreeMsg{loop: inst, action: action, err: err}
+		}
+
+		if action.remove {
+			return worktreeMsg{loop: inst, action: action, err: repo.Remove(wt, action.force)}
+		}
+		if err := repo.Merge(wt); err != nil {
+			return worktreeMsg{loop: inst, action: action, err: err}
+		}
+		return worktreeMsg{loop: inst, action: action, pushErr: repo.Push(wt.Base)}
+	}
+}
+
+// handleWorktreeMsg records a finished worktree action. A refused remove is
+// offered again as a forced one.
+func (m *Model) handleWorktreeMsg(msg worktreeMsg) tea.Cmd {
+	inst := msg.loop
+	inst.worktreeBusy = false
+	inst.worktreeFailed = msg.err != nil || msg.pushErr != nil
+	wt, _ := inst.state.GetWorktree()
+
+	switch {
+	case msg.err != nil && msg.action.remove && !msg.action.force && inst == m.loop():
+		inst.worktreeNote = msg.err.Error()
+		m.worktreePending = &worktreeAction{remove: true, force: true}
+		return nil
+	case msg.err != nil:
+		inst.worktreeNote = msg.err.Error()
+		return nil
+	case msg.action.remove:
+		inst.state.SetWorktree(nil)
+		inst.worktreeRemoved = true
+		inst.worktreeNote = fmt.Sprintf("Removed worktree and branch %s", wt.Branch)
+		return nil
+	case msg.pushErr != nil:
+		inst.worktreeNote = fmt.Sprintf("Merged %s into %s, but %v", wt.Branch, wt.Base, msg.pushErr)
+		return fetchGitBranch(inst.state)
+	default:
+		inst.worktreeNote = fmt.Sprintf("Merged %s into %s and pushed", wt.Branch, wt.Base)
+		return fetchGitBranch(inst.state)
+	}
+}
+
+// worktreePrompt asks to confirm the pending worktree action.
+func (m *Model) worktreePrompt() string {
+	wt, _ := m.state.GetWorktree()
+	switch {
+	case m.worktreePending.force:
+		return fmt.Sprintf("Discard the work in %s and remove it anyway? (y/n)", wt.Branch)
+	case m.worktreePending.remove:
+		return fmt.Sprintf("Remove worktree %s and delete branch %s? (y/n)", wt.Path, wt.Branch)
+	default:
+		return fmt.Sprintf("Merge %s into %s and push? (y/n)", wt.Branch, wt.Base)
+	}
+}
+
+// renderWorktree renders the shown loop's worktree and the outcome of the
+// last action on it.
+func (m *Model) renderWorktree() []string {
+	inst := m.loop()
+	var lines []string
+
+	if wt, ok := inst.state.GetWorktree(); ok {
+		lines = append(lines, fmt.Sprintf("Worktree: %s (from %s)", wt.Path, wt.Base))
+		switch {
+		case inst.worktreeBusy:
+			lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render("Working on worktree..."))
+		case !inst.active():
+			lines = append(lines, lipgloss.NewStyle().Faint(true).
+				Render(fmt.Sprintf("M: merge into %s | D: remove worktree", wt.Base)))
+		}
+	}
+
+	if inst.worktreeNote != "" {
+		color := lipgloss.Color("10")
+		if inst.worktreeFailed {
+			color = lipgloss.Color("9")
+		}
+		lines = append(lines, lipgloss.NewStyle().Foreground(color).Render(inst.worktreeNote))
+	}
+	return lines
+}